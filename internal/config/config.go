@@ -21,6 +21,7 @@ var (
 
 type Config struct {
 	LowercaseKeywords bool                 `json:"lowercaseKeywords" yaml:"lowercaseKeywords"`
+	ColumnTypeHints   bool                 `json:"columnTypeHints" yaml:"columnTypeHints"`
 	Connections       []*database.DBConfig `json:"connections" yaml:"connections"`
 }
 
@@ -2,12 +2,36 @@ package database
 
 import (
 	"context"
+	"slices"
 	"sort"
 	"strings"
 )
 
+// CachePhase is a step of building the cache.
+type CachePhase string
+
+const (
+	CachePhaseSchemas     CachePhase = "schemas"
+	CachePhaseTables      CachePhase = "tables"
+	CachePhaseColumns     CachePhase = "columns"
+	CachePhaseForeignKeys CachePhase = "foreign keys"
+)
+
+// CacheProgress receives the progress of building the cache.
+type CacheProgress interface {
+	// Report is called when phase starts, after done of the total phases.
+	Report(phase CachePhase, done, total int)
+	// End is called when the cache is built or failed to be.
+	End(err error)
+}
+
+// CacheProgressFunc starts reporting the progress of building the cache
+// described by title.
+type CacheProgressFunc func(title string) CacheProgress
+
 type DBCacheGenerator struct {
-	repo DBRepository
+	repo     DBRepository
+	progress CacheProgress
 }
 
 func NewDBCacheUpdater(repo DBRepository) *DBCacheGenerator {
@@ -16,8 +40,27 @@ func NewDBCacheUpdater(repo DBRepository) *DBCacheGenerator {
 	}
 }
 
-func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache, error) {
-	var err error
+// SetProgress sets the receiver of the progress of the next generation.
+func (u *DBCacheGenerator) SetProgress(progress CacheProgress) {
+	u.progress = progress
+}
+
+func (u *DBCacheGenerator) report(phase CachePhase, done, total int) {
+	if u.progress != nil {
+		u.progress.Report(phase, done, total)
+	}
+}
+
+func (u *DBCacheGenerator) end(err error) {
+	if u.progress != nil {
+		u.progress.End(err)
+	}
+}
+
+func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (cache *DBCache, err error) {
+	defer func() { u.end(err) }()
+
+	u.report(CachePhaseSchemas, 0, 4)
 	dbCache := &DBCache{}
 	dbCache.defaultSchema, err = u.repo.CurrentSchema(ctx)
 	if err != nil {
@@ -40,6 +83,7 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 		}
 		dbCache.defaultSchema = dbCache.Schemas[topKey]
 	}
+	u.report(CachePhaseTables, 1, 4)
 	schemaTables, err := u.repo.SchemaTables(ctx)
 	if err != nil {
 		return nil, err
@@ -49,10 +93,12 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 		dbCache.SchemaTables[strings.ToUpper(index)] = element
 	}
 
+	u.report(CachePhaseColumns, 2, 4)
 	dbCache.ColumnsWithParent, err = u.genColumnCacheCurrent(ctx, dbCache.defaultSchema)
 	if err != nil {
 		return nil, err
 	}
+	u.report(CachePhaseForeignKeys, 3, 4)
 	dbCache.ForeignKeys, err = u.genForeignKeysCache(ctx, dbCache.defaultSchema)
 	if err != nil {
 		return nil, err
@@ -60,7 +106,10 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 	return dbCache, nil
 }
 
-func (u *DBCacheGenerator) GenerateDBCacheSecondary(ctx context.Context) (map[string][]*ColumnDesc, error) {
+func (u *DBCacheGenerator) GenerateDBCacheSecondary(ctx context.Context) (col map[string][]*ColumnDesc, err error) {
+	defer func() { u.end(err) }()
+
+	u.report(CachePhaseColumns, 0, 1)
 	return u.genColumnCacheAll(ctx)
 }
 
@@ -151,6 +200,8 @@ func (dc *DBCache) SortedSchemas() []string {
 
 func (dc *DBCache) SortedTablesByDBName(dbName string) (tbls []string, ok bool) {
 	tbls, ok = dc.SchemaTables[strings.ToUpper(dbName)]
+	// The cache is shared by the requests, so a copy is sorted
+	tbls = slices.Clone(tbls)
 	sort.Strings(tbls)
 	return
 }
@@ -160,6 +211,24 @@ func (dc *DBCache) SortedTables() []string {
 	return tbls
 }
 
+// Table looks up tableName case-insensitively and returns the schema and
+// table names as stored in the cache. An empty dbName searches the default
+// schema first and then the other schemas.
+func (dc *DBCache) Table(dbName, tableName string) (db, tbl string, ok bool) {
+	dbNames := []string{dbName}
+	if dbName == "" {
+		dbNames = append([]string{dc.defaultSchema}, dc.SortedSchemas()...)
+	}
+	for _, dbName := range dbNames {
+		for _, t := range dc.SchemaTables[strings.ToUpper(dbName)] {
+			if strings.EqualFold(t, tableName) {
+				return dbName, t, true
+			}
+		}
+	}
+	return "", "", false
+}
+
 func (dc *DBCache) ColumnDescs(tableName string) (cols []*ColumnDesc, ok bool) {
 	cols, ok = dc.ColumnsWithParent[columnDatabaseKey(dc.defaultSchema, tableName)]
 	return
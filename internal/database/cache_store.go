@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CacheStore keeps the database caches by connection, so that the servers
+// of the clients connected to the same database share them.
+type CacheStore struct {
+	mu     sync.Mutex
+	caches map[string]*CacheRef
+}
+
+func NewCacheStore() *CacheStore {
+	return &CacheStore{
+		caches: make(map[string]*CacheRef),
+	}
+}
+
+// Load returns the cache of the database of cfg, or nil if it has not been
+// stored. A nil store has no caches.
+func (s *CacheStore) Load(cfg *DBConfig) *CacheRef {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.caches[cacheKey(cfg)]
+}
+
+// Store keeps cache as the cache of the database of cfg. A nil store keeps
+// nothing.
+func (s *CacheStore) Store(cfg *DBConfig, cache *CacheRef) {
+	if s == nil || cache == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caches[cacheKey(cfg)] = cache
+}
+
+// cacheKey identifies the database of cfg, leaving out the alias and the
+// credentials.
+func cacheKey(cfg *DBConfig) string {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s|%d|%s|%s", cfg.Driver, cfg.DataSourceName, cfg.Proto, cfg.User, cfg.Host, cfg.Port, cfg.Path, cfg.DBName)
+	if cfg.SSHCfg != nil {
+		key += fmt.Sprintf("|ssh:%s@%s:%d", cfg.SSHCfg.User, cfg.SSHCfg.Host, cfg.SSHCfg.Port)
+	}
+	return key
+}
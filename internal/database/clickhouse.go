@@ -348,6 +348,10 @@ func (db *clickhouseSQLDBRepository) Query(ctx context.Context, query string) (*
 	return db.Conn.QueryContext(ctx, query)
 }
 
+func (db *clickhouseSQLDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	return nil, ErrNotImplementation
+}
+
 func (db *clickhouseSQLDBRepository) SchemaTables(ctx context.Context) (map[string][]string, error) {
 	rows, err := db.Conn.QueryContext(
 		ctx,
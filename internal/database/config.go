@@ -31,12 +31,24 @@ type DBConfig struct {
 	DBName         string                 `json:"dbName" yaml:"dbName"`
 	Params         map[string]string      `json:"params" yaml:"params"`
 	SSHCfg         *SSHConfig             `json:"sshConfig" yaml:"sshConfig"`
+	// MaxRows is the number of rows executeQuery fetches from a query before
+	// stopping it. 0 fetches all the rows.
+	MaxRows int `json:"maxRows" yaml:"maxRows"`
+	// QueryTimeout is the number of seconds a statement of executeQuery may
+	// run before it is cancelled. 0 disables the timeout.
+	QueryTimeout int `json:"queryTimeout" yaml:"queryTimeout"`
 }
 
 func (c *DBConfig) Validate() error {
 	if c.Driver == "" {
 		return errors.New("required: connections[].driver")
 	}
+	if c.MaxRows < 0 {
+		return errors.New("invalid: connections[].maxRows")
+	}
+	if c.QueryTimeout < 0 {
+		return errors.New("invalid: connections[].queryTimeout")
+	}
 
 	switch c.Driver {
 	case
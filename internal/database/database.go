@@ -33,6 +33,19 @@ type DBRepository interface {
 	Exec(ctx context.Context, query string) (sql.Result, error)
 	Query(ctx context.Context, query string) (*sql.Rows, error)
 	DescribeForeignKeysBySchema(ctx context.Context, schemaName string) ([]*ForeignKey, error)
+	// Explain returns the execution plan of query, explained on conn, or on
+	// any connection of the repository if conn is nil.
+	Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error)
+}
+
+// QueryCanceler is implemented by the repositories of the databases that can
+// cancel a query from another connection, as cancelling the context of a
+// query does not always stop it on the database server.
+type QueryCanceler interface {
+	// ConnectionID returns the ID of conn on the database server.
+	ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error)
+	// CancelQuery cancels the query running on the connection id.
+	CancelQuery(ctx context.Context, id int64) error
 }
 
 type DBOption struct {
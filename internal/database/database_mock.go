@@ -18,6 +18,7 @@ type MockDBRepository struct {
 	MockExec                          func(context.Context, string) (sql.Result, error)
 	MockQuery                         func(context.Context, string) (*sql.Rows, error)
 	MockDescribeForeignKeysBySchema   func(context.Context, string) ([]*ForeignKey, error)
+	MockExplain                       func(context.Context, *sql.Conn, string) ([]*PlanNode, error)
 }
 
 func NewMockDBRepository(_ *sql.DB) DBRepository {
@@ -65,6 +66,9 @@ func NewMockDBRepository(_ *sql.DB) DBRepository {
 		MockDescribeForeignKeysBySchema: func(ctx context.Context, schemaName string) ([]*ForeignKey, error) {
 			return foreignKeys, nil
 		},
+		MockExplain: func(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+			return dummyPlan, nil
+		},
 	}
 }
 
@@ -116,6 +120,10 @@ func (m *MockDBRepository) DescribeForeignKeysBySchema(ctx context.Context, sche
 	return m.MockDescribeForeignKeysBySchema(ctx, schemaName)
 }
 
+func (m *MockDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	return m.MockExplain(ctx, conn, query)
+}
+
 var dummyDatabases = []string{
 	"information_schema",
 	"mysql",
@@ -533,6 +541,33 @@ var foreignKeys = []*ForeignKey{
 	},
 }
 
+var (
+	dummyPlanCost = 25.5
+	dummyPlanRows = 4079.0
+	dummyScanCost = 12.5
+	dummyScanRows = 239.0
+)
+
+var dummyPlan = []*PlanNode{
+	{
+		Operation: "Hash Join",
+		Cost:      &dummyPlanCost,
+		Rows:      &dummyPlanRows,
+		Children: []*PlanNode{
+			{
+				Operation: "Seq Scan on city",
+				Cost:      &dummyPlanCost,
+				Rows:      &dummyPlanRows,
+			},
+			{
+				Operation: "Seq Scan on country",
+				Cost:      &dummyScanCost,
+				Rows:      &dummyScanRows,
+			},
+		},
+	},
+}
+
 type MockResult struct {
 	MockLastInsertID func() (int64, error)
 	MockRowsAffected func() (int64, error)
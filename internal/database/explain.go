@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PlanNode is an operation of the execution plan of a query.
+type PlanNode struct {
+	Operation string `json:"operation"`
+	// Cost is the estimated cost, in the unit of the database. It is nil if
+	// the database does not estimate it.
+	Cost *float64 `json:"cost,omitempty"`
+	// Rows is the estimated number of rows. It is nil if the database does
+	// not estimate it.
+	Rows     *float64    `json:"rows,omitempty"`
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// RenderPlan renders the plan as an indented tree, with the estimates of each
+// operation.
+func RenderPlan(nodes []*PlanNode) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		renderPlanNode(&b, node, 0)
+	}
+	return b.String()
+}
+
+func renderPlanNode(b *strings.Builder, node *PlanNode, depth int) {
+	if depth > 0 {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("-> ")
+	}
+	b.WriteString(node.Operation)
+
+	var estimates []string
+	if node.Cost != nil {
+		estimates = append(estimates, fmt.Sprintf("cost=%.2f", *node.Cost))
+	}
+	if node.Rows != nil {
+		estimates = append(estimates, fmt.Sprintf("rows=%.0f", *node.Rows))
+	}
+	if len(estimates) > 0 {
+		fmt.Fprintf(b, "  (%s)", strings.Join(estimates, " "))
+	}
+	b.WriteString("\n")
+
+	for _, child := range node.Children {
+		renderPlanNode(b, child, depth+1)
+	}
+}
+
+// explainQuery returns query without the semicolon ending it, to be wrapped
+// in an EXPLAIN statement.
+func explainQuery(query string) string {
+	return strings.TrimSuffix(strings.TrimSpace(query), ";")
+}
+
+// planEstimate converts an estimate found in a plan, as a number or a string,
+// to a float.
+func planEstimate(v interface{}) *float64 {
+	switch v := v.(type) {
+	case float64:
+		return &v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		return &f
+	}
+	return nil
+}
+
+// queryer runs the statements of Explain on a database or on one of its
+// connections.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// explainQueryer returns conn, or db if conn is nil.
+func explainQueryer(db *sql.DB, conn *sql.Conn) queryer {
+	if conn != nil {
+		return conn
+	}
+	return db
+}
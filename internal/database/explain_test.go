@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderPlan(t *testing.T) {
+	cost, rows := 25.5, 4079.0
+	nodes := []*PlanNode{
+		{
+			Operation: "Hash Join",
+			Cost:      &cost,
+			Rows:      &rows,
+			Children: []*PlanNode{
+				{Operation: "Seq Scan on city"},
+				{
+					Operation: "Hash",
+					Rows:      &rows,
+					Children: []*PlanNode{
+						{Operation: "Seq Scan on country", Cost: &cost},
+					},
+				},
+			},
+		},
+	}
+	want := "Hash Join  (cost=25.50 rows=4079)\n" +
+		"  -> Seq Scan on city\n" +
+		"  -> Hash  (rows=4079)\n" +
+		"    -> Seq Scan on country  (cost=25.50)\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func Test_parsePostgreSQLPlan(t *testing.T) {
+	plan := `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Join Type": "Left",
+      "Total Cost": 31.23,
+      "Plan Rows": 4079,
+      "Plans": [
+        {
+          "Node Type": "Seq Scan",
+          "Relation Name": "city",
+          "Alias": "ci",
+          "Total Cost": 15.79,
+          "Plan Rows": 4079
+        },
+        {
+          "Node Type": "Index Scan",
+          "Index Name": "country_pkey",
+          "Relation Name": "country",
+          "Alias": "country",
+          "Total Cost": 8.29,
+          "Plan Rows": 1
+        }
+      ]
+    }
+  }
+]`
+	nodes, err := parsePostgreSQLPlan([]byte(plan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Hash Left Join  (cost=31.23 rows=4079)\n" +
+		"  -> Seq Scan on city ci  (cost=15.79 rows=4079)\n" +
+		"  -> Index Scan using country_pkey on country  (cost=8.29 rows=1)\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func Test_parseMySQLPlan(t *testing.T) {
+	plan := `{
+  "query_block": {
+    "select_id": 1,
+    "cost_info": {"query_cost": "1206.80"},
+    "ordering_operation": {
+      "using_filesort": true,
+      "nested_loop": [
+        {
+          "table": {
+            "table_name": "city",
+            "access_type": "ALL",
+            "rows_examined_per_scan": 4046,
+            "cost_info": {"prefix_cost": "410.35"}
+          }
+        },
+        {
+          "table": {
+            "table_name": "country",
+            "access_type": "eq_ref",
+            "key": "PRIMARY",
+            "rows_examined_per_scan": 1,
+            "cost_info": {"prefix_cost": "1206.80"}
+          }
+        }
+      ]
+    }
+  }
+}`
+	nodes, err := parseMySQLPlan([]byte(plan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Query block #1  (cost=1206.80)\n" +
+		"  -> Sort\n" +
+		"    -> Nested loop\n" +
+		"      -> ALL on city  (cost=410.35 rows=4046)\n" +
+		"      -> eq_ref on country using PRIMARY  (cost=1206.80 rows=1)\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+
+	// The operations keep the order of the plan, not of their keys
+	plan = `{
+  "query_block": {
+    "select_id": 1,
+    "table": {
+      "table_name": "country",
+      "access_type": "ALL",
+      "rows_examined_per_scan": 239
+    },
+    "select_list_subqueries": [
+      {
+        "query_block": {
+          "select_id": 2,
+          "table": {
+            "table_name": "city",
+            "access_type": "ref",
+            "key": "CountryCode",
+            "rows_examined_per_scan": 17
+          }
+        }
+      }
+    ]
+  }
+}`
+	nodes, err = parseMySQLPlan([]byte(plan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "Query block #1\n" +
+		"  -> ALL on country  (rows=239)\n" +
+		"  -> Query block #2\n" +
+		"    -> ref on city using CountryCode  (rows=17)\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+
+	if _, err := parseMySQLPlan([]byte("[]")); err == nil {
+		t.Error("expected error for the plan that is not an object")
+	}
+}
+
+func Test_parseOracleXPlan(t *testing.T) {
+	lines := []string{
+		"Plan hash value: 615168685",
+		"",
+		"-------------------------------------------------------------",
+		"| Id  | Operation          | Name    | Rows  | Cost (%CPU)|",
+		"-------------------------------------------------------------",
+		"|   0 | SELECT STATEMENT   |         |  1500K|  4012   (1)|",
+		"|*  1 |  HASH JOIN         |         |  1500K|  4012   (1)|",
+		"|   2 |   TABLE ACCESS FULL| COUNTRY |   239 |     3   (0)|",
+		"|   3 |   TABLE ACCESS FULL| CITY    |  4079 |    11   (0)|",
+		"-------------------------------------------------------------",
+	}
+	nodes, err := parseOracleXPlan(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT STATEMENT  (cost=4012.00 rows=1500000)\n" +
+		"  -> HASH JOIN  (cost=4012.00 rows=1500000)\n" +
+		"    -> TABLE ACCESS FULL COUNTRY  (cost=3.00 rows=239)\n" +
+		"    -> TABLE ACCESS FULL CITY  (cost=11.00 rows=4079)\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+
+	if _, err := parseOracleXPlan([]string{"Error: cannot fetch plan for statement_id 'sqls'"}); err == nil {
+		t.Error("expected error for the output without plan table")
+	}
+}
+
+func TestSQLite3Explain(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.Exec("CREATE TABLE city (id INTEGER, name TEXT); CREATE INDEX city_id ON city (id)"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &SQLite3DBRepository{Conn: conn}
+	nodes, err := repo.Explain(context.Background(), nil, "SELECT name FROM city WHERE id IN (SELECT id FROM city WHERE name = 'Kabul');")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SEARCH city USING INDEX city_id (id=?)\n" +
+		"LIST SUBQUERY 1\n" +
+		"  -> SCAN city\n"
+	if got := RenderPlan(nodes); got != want {
+		t.Errorf("unmatch plan (- want, + got):\n%s", cmp.Diff(want, got))
+	}
+}
@@ -270,6 +270,10 @@ func (db *H2DBRepository) Query(ctx context.Context, query string) (*sql.Rows, e
 	return db.Conn.QueryContext(ctx, query)
 }
 
+func (db *H2DBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	return nil, ErrNotImplementation
+}
+
 func (db *H2DBRepository) DescribeForeignKeysBySchema(ctx context.Context, schemaName string) ([]*ForeignKey, error) {
 	return nil, fmt.Errorf("describe foreign keys is not supported")
 }
@@ -371,6 +371,10 @@ func (db *MssqlDBRepository) Query(ctx context.Context, query string) (*sql.Rows
 	return db.Conn.QueryContext(ctx, query)
 }
 
+func (db *MssqlDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	return nil, ErrNotImplementation
+}
+
 func genMssqlConfig(connCfg *DBConfig) (string, error) {
 	if connCfg.DataSourceName != "" {
 		return connCfg.DataSourceName, nil
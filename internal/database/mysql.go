@@ -1,8 +1,11 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -336,3 +339,169 @@ func (db *MySQLDBRepository) Exec(ctx context.Context, query string) (sql.Result
 func (db *MySQLDBRepository) Query(ctx context.Context, query string) (*sql.Rows, error) {
 	return db.Conn.QueryContext(ctx, query)
 }
+
+func (db *MySQLDBRepository) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (db *MySQLDBRepository) CancelQuery(ctx context.Context, id int64) error {
+	// KILL does not take placeholders
+	_, err := db.Conn.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", id))
+	return err
+}
+
+func (db *MySQLDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	var plan []byte
+	if err := explainQueryer(db.Conn, conn).QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+explainQuery(query)).Scan(&plan); err != nil {
+		return nil, err
+	}
+	return parseMySQLPlan(plan)
+}
+
+// parseMySQLPlan parses the output of EXPLAIN FORMAT=JSON.
+func parseMySQLPlan(data []byte) ([]*PlanNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	v, err := decodeMySQLPlanValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the plan, %w", err)
+	}
+	plan, ok := v.(*mysqlPlanObject)
+	if !ok {
+		return nil, errors.New("cannot parse the plan, expected an object")
+	}
+	return mysqlPlanNodes(plan), nil
+}
+
+// mysqlPlanObject is an object of the plan. Its keys are kept in the order of
+// the plan, which is the order the operations run in.
+type mysqlPlanObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// decodeMySQLPlanValue decodes the next value of dec, with the objects
+// decoded as *mysqlPlanObject.
+func decodeMySQLPlanValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &mysqlPlanObject{values: map[string]interface{}{}}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := tok.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %v as a key", tok)
+			}
+			v, err := decodeMySQLPlanValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.values[key]; !dup {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = v
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case json.Delim('['):
+		var arr []interface{}
+		for dec.More() {
+			v, err := decodeMySQLPlanValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return tok, nil
+}
+
+// mysqlPlanNodes returns the operations found in an object of the plan, in
+// the order of its keys. The objects that are not operations, such as the
+// subqueries attached to a table, are walked for the operations they hold.
+func mysqlPlanNodes(obj *mysqlPlanObject) []*PlanNode {
+	var nodes []*PlanNode
+	for _, k := range obj.keys {
+		switch v := obj.values[k].(type) {
+		case *mysqlPlanObject:
+			if node := mysqlPlanNode(k, v); node != nil {
+				nodes = append(nodes, node)
+			} else {
+				nodes = append(nodes, mysqlPlanNodes(v)...)
+			}
+		case []interface{}:
+			var children []*PlanNode
+			for _, elem := range v {
+				if elem, ok := elem.(*mysqlPlanObject); ok {
+					children = append(children, mysqlPlanNodes(elem)...)
+				}
+			}
+			if k == "nested_loop" {
+				nodes = append(nodes, &PlanNode{Operation: "Nested loop", Children: children})
+			} else {
+				nodes = append(nodes, children...)
+			}
+		}
+	}
+	return nodes
+}
+
+func mysqlPlanNode(key string, obj *mysqlPlanObject) *PlanNode {
+	node := &PlanNode{}
+	switch key {
+	case "query_block":
+		node.Operation = "Query block"
+		if id, ok := obj.values["select_id"].(float64); ok {
+			node.Operation += fmt.Sprintf(" #%.0f", id)
+		}
+		node.Cost = mysqlCost(obj, "query_cost")
+	case "table":
+		access, _ := obj.values["access_type"].(string)
+		table, _ := obj.values["table_name"].(string)
+		node.Operation = fmt.Sprintf("%s on %s", access, table)
+		if index, ok := obj.values["key"].(string); ok {
+			node.Operation += " using " + index
+		}
+		node.Rows = planEstimate(obj.values["rows_examined_per_scan"])
+		node.Cost = mysqlCost(obj, "prefix_cost")
+	case "union_result":
+		node.Operation = "Union"
+		node.Cost = mysqlCost(obj, "query_cost")
+	case "ordering_operation":
+		node.Operation = "Sort"
+	case "grouping_operation":
+		node.Operation = "Group"
+	case "duplicates_removal":
+		node.Operation = "Distinct"
+	case "materialized_from_subquery":
+		node.Operation = "Materialize"
+	default:
+		return nil
+	}
+	node.Children = mysqlPlanNodes(obj)
+	return node
+}
+
+func mysqlCost(obj *mysqlPlanObject, name string) *float64 {
+	costInfo, ok := obj.values["cost_info"].(*mysqlPlanObject)
+	if !ok {
+		return nil
+	}
+	return planEstimate(costInfo.values[name])
+}
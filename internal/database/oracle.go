@@ -3,8 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	_ "github.com/godror/godror"
 	"github.com/sqls-server/sqls/dialect"
@@ -265,3 +267,146 @@ func (db *OracleDBRepository) Exec(ctx context.Context, query string) (sql.Resul
 func (db *OracleDBRepository) Query(ctx context.Context, query string) (*sql.Rows, error) {
 	return db.Conn.QueryContext(ctx, query)
 }
+
+// oracleStatementID identifies the plans of sqls in the plan table.
+const oracleStatementID = "sqls"
+
+func (db *OracleDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	// The plan table is private to the session, so both statements run on the
+	// same connection
+	if conn == nil {
+		var err error
+		conn, err = db.Conn.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+	}
+
+	explain := fmt.Sprintf("EXPLAIN PLAN SET STATEMENT_ID = '%s' FOR %s", oracleStatementID, explainQuery(query))
+	if _, err := conn.ExecContext(ctx, explain); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, `
+SELECT
+  plan_table_output
+FROM
+  TABLE(DBMS_XPLAN.DISPLAY(NULL, :1, 'BASIC +ROWS +COST'))
+`, oracleStatementID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var lines []string
+	for rows.Next() {
+		var line sql.NullString
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return parseOracleXPlan(lines)
+}
+
+// parseOracleXPlan parses the plan table displayed by DBMS_XPLAN, in which
+// the operations are indented by their depth:
+//
+//	| Id  | Operation          | Name | Rows  | Cost (%CPU)|
+//	|   0 | SELECT STATEMENT   |      |    82 |     3   (0)|
+//	|   1 |  TABLE ACCESS FULL | EMP  |    82 |     3   (0)|
+func parseOracleXPlan(lines []string) ([]*PlanNode, error) {
+	var header []string
+	var roots []*PlanNode
+	var stack []*PlanNode
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		if header == nil {
+			if strings.TrimSpace(cells[0]) == "Id" {
+				header = make([]string, len(cells))
+				for i, cell := range cells {
+					header[i] = strings.TrimSpace(cell)
+				}
+			}
+			continue
+		}
+		if len(cells) != len(header) {
+			continue
+		}
+
+		var (
+			op, name string
+			depth    int
+			node     = &PlanNode{}
+		)
+		for i, cell := range cells {
+			switch {
+			case header[i] == "Operation":
+				trimmed := strings.TrimLeft(cell, " ")
+				// The operations are indented by one space per depth after
+				// the space separating them from the bar
+				depth = len(cell) - len(trimmed) - 1
+				op = strings.TrimSpace(trimmed)
+			case header[i] == "Name":
+				name = strings.TrimSpace(cell)
+			case header[i] == "Rows":
+				node.Rows = parseOracleEstimate(strings.TrimSpace(cell))
+			case strings.HasPrefix(header[i], "Cost"):
+				if fields := strings.Fields(cell); len(fields) > 0 {
+					node.Cost = parseOracleEstimate(fields[0])
+				}
+			}
+		}
+		node.Operation = strings.TrimSpace(op + " " + name)
+
+		if depth < 0 {
+			depth = 0
+		}
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = stack[:depth]
+		if depth == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("cannot parse the plan, the plan table was not found")
+	}
+	return roots, nil
+}
+
+// parseOracleEstimate parses an estimate of DBMS_XPLAN, which abbreviates the
+// large ones with K, M and G.
+func parseOracleEstimate(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'K':
+		mult = 1e3
+	case 'M':
+		mult = 1e6
+	case 'G':
+		mult = 1e9
+	}
+	if mult != 1 {
+		s = s[:len(s)-1]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	f *= mult
+	return &f
+}
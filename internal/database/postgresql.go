@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
@@ -391,6 +392,87 @@ func (db *PostgreSQLDBRepository) Query(ctx context.Context, query string) (*sql
 	return db.Conn.QueryContext(ctx, query)
 }
 
+func (db *PostgreSQLDBRepository) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pid int64
+	if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		return 0, err
+	}
+	return pid, nil
+}
+
+func (db *PostgreSQLDBRepository) CancelQuery(ctx context.Context, id int64) error {
+	_, err := db.Conn.ExecContext(ctx, "SELECT pg_cancel_backend($1)", id)
+	return err
+}
+
+func (db *PostgreSQLDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	var plan []byte
+	if err := explainQueryer(db.Conn, conn).QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+explainQuery(query)).Scan(&plan); err != nil {
+		return nil, err
+	}
+	return parsePostgreSQLPlan(plan)
+}
+
+type postgreSQLPlanNode struct {
+	NodeType     string                `json:"Node Type"`
+	JoinType     string                `json:"Join Type"`
+	IndexName    string                `json:"Index Name"`
+	RelationName string                `json:"Relation Name"`
+	Alias        string                `json:"Alias"`
+	TotalCost    float64               `json:"Total Cost"`
+	PlanRows     float64               `json:"Plan Rows"`
+	Plans        []*postgreSQLPlanNode `json:"Plans"`
+}
+
+// parsePostgreSQLPlan parses the output of EXPLAIN (FORMAT JSON), naming the
+// operations as EXPLAIN does in text.
+func parsePostgreSQLPlan(data []byte) ([]*PlanNode, error) {
+	var plans []struct {
+		Plan *postgreSQLPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("cannot parse the plan, %w", err)
+	}
+	nodes := []*PlanNode{}
+	for _, p := range plans {
+		if p.Plan != nil {
+			nodes = append(nodes, p.Plan.planNode())
+		}
+	}
+	return nodes, nil
+}
+
+func (p *postgreSQLPlanNode) planNode() *PlanNode {
+	op := p.NodeType
+	if p.JoinType != "" && p.JoinType != "Inner" {
+		if strings.HasSuffix(op, " Join") {
+			op = strings.TrimSuffix(op, "Join") + p.JoinType + " Join"
+		} else {
+			op += " " + p.JoinType + " Join"
+		}
+	}
+	if p.IndexName != "" {
+		op += " using " + p.IndexName
+	}
+	if p.RelationName != "" {
+		op += " on " + p.RelationName
+		if p.Alias != "" && p.Alias != p.RelationName {
+			op += " " + p.Alias
+		}
+	}
+
+	cost, rows := p.TotalCost, p.PlanRows
+	node := &PlanNode{
+		Operation: op,
+		Cost:      &cost,
+		Rows:      &rows,
+	}
+	for _, child := range p.Plans {
+		node.Children = append(node.Children, child.planNode())
+	}
+	return node
+}
+
 func genPostgresConfig(connCfg *DBConfig) (string, error) {
 	if connCfg.DataSourceName != "" {
 		return connCfg.DataSourceName, nil
@@ -225,6 +225,36 @@ var createIgnore = map[string]bool{
 	"UNLOGGED":   true,
 }
 
+// verbMap is the map of statement verbs which are not covered by queryMap
+// and execMap, mostly dialect specific statements.
+var verbMap = map[string]bool{
+	"ATTACH":   true, // attach a database file (sqlite)
+	"CALL":     true, // call a stored procedure
+	"DETACH":   true, // detach a database file (sqlite)
+	"FLUSH":    true, // flush internal caches (mysql)
+	"KILL":     true, // kill a connection or query (mysql)
+	"MERGE":    true, // conditionally insert, update or delete rows
+	"OPTIMIZE": true, // optimize a table (mysql)
+	"RENAME":   true, // rename a table (mysql)
+	"REPLACE":  true, // insert or replace rows (mysql, sqlite)
+	"USE":      true, // change the current database (mysql, mssql)
+}
+
+// IsStatementVerb reports whether word is a known first keyword of a SQL
+// statement, for example SELECT, INSERT or CREATE.
+func IsStatementVerb(word string) bool {
+	w := strings.ToUpper(word)
+	if queryMap[w] || verbMap[w] {
+		return true
+	}
+	for typ := range execMap {
+		if typ == w || strings.HasPrefix(typ, w+" ") {
+			return true
+		}
+	}
+	return false
+}
+
 func splitMultiSep(s string, sep []string) []string {
 	var ret []string
 	ret = strings.Split(s, sep[0])
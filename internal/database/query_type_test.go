@@ -90,3 +90,26 @@ func TestQueryExecType(t *testing.T) {
 		})
 	}
 }
+
+func TestIsStatementVerb(t *testing.T) {
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{word: "select", want: true},
+		{word: "WITH", want: true},
+		{word: "create", want: true},
+		{word: "Alter", want: true},
+		{word: "use", want: true},
+		{word: "SELEC", want: false},
+		{word: "TABLE", want: false},
+		{word: "city", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := IsStatementVerb(tt.word); got != tt.want {
+				t.Errorf("IsStatementVerb(%q) = %v, want %v", tt.word, got, tt.want)
+			}
+		})
+	}
+}
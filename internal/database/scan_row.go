@@ -4,10 +4,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Column is a column of the result of a query.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the database type name reported by the driver, such as
+	// "VARCHAR" or "INT4". It is empty if the driver does not know it.
+	Type string `json:"type"`
+}
+
 func Columns(rows *sql.Rows) ([]string, error) {
 	var cols []string
 	var err error
@@ -26,6 +36,27 @@ func Columns(rows *sql.Rows) ([]string, error) {
 	return cols, nil
 }
 
+// ColumnTypes returns the columns of rows with their database type names.
+func ColumnTypes(rows *sql.Rows) ([]*Column, error) {
+	names, err := Columns(rows)
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get query column types, %w", err)
+	}
+
+	cols := make([]*Column, len(names))
+	for i, name := range names {
+		cols[i] = &Column{Name: name}
+		if i < len(types) {
+			cols[i].Type = types[i].DatabaseTypeName()
+		}
+	}
+	return cols, nil
+}
+
 func ScanRows(rows *sql.Rows, columnLength int) ([][]string, error) {
 	stringRows := [][]string{}
 	for rows.Next() {
@@ -85,3 +116,99 @@ func sqlValToString(pointer interface{}) (string, error) {
 
 	return res, nil
 }
+
+// ScanTypedRows scans rows keeping the values typed, so that numbers, booleans
+// and NULL can be told apart from strings once encoded in JSON.
+func ScanTypedRows(rows *sql.Rows, columnLength int) ([][]interface{}, error) {
+	typedRows, _, err := NewRowCursor(rows, columnLength).Fetch(0)
+	return typedRows, err
+}
+
+// RowCursor streams the rows of a query, scanning them as ScanTypedRows does,
+// so that large results can be read a page at a time.
+type RowCursor struct {
+	rows         *sql.Rows
+	columnLength int
+	// next is set when rows has been advanced to a row not scanned yet.
+	next bool
+	done bool
+}
+
+func NewRowCursor(rows *sql.Rows, columnLength int) *RowCursor {
+	return &RowCursor{
+		rows:         rows,
+		columnLength: columnLength,
+	}
+}
+
+// Fetch scans at most limit rows, or all the rows left if limit is 0. more
+// reports whether rows are left after them.
+func (c *RowCursor) Fetch(limit int) (typedRows [][]interface{}, more bool, err error) {
+	typedRows = [][]interface{}{}
+	for !c.done && (limit <= 0 || len(typedRows) < limit) {
+		if !c.next && !c.rows.Next() {
+			c.done = true
+			break
+		}
+		c.next = false
+
+		rowBuffer := make([]interface{}, c.columnLength)
+		for i := range rowBuffer {
+			rowBuffer[i] = new(interface{})
+		}
+		if err := c.rows.Scan(rowBuffer...); err != nil {
+			return nil, false, err
+		}
+		typedRow := make([]interface{}, c.columnLength)
+		for i, buf := range rowBuffer {
+			typedRow[i] = sqlValToJSON(*buf.(*interface{}))
+		}
+		typedRows = append(typedRows, typedRow)
+	}
+	// Look ahead to tell whether the limit truncated the rows
+	if !c.done && !c.next {
+		c.next = c.rows.Next()
+		c.done = !c.next
+	}
+	if c.done {
+		if err := c.rows.Err(); err != nil {
+			return nil, false, err
+		}
+	}
+	return typedRows, !c.done, nil
+}
+
+// Close closes the rows, stopping the fetch of the rows left.
+func (c *RowCursor) Close() error {
+	c.done = true
+	return c.rows.Close()
+}
+
+// sqlValToJSON converts a value scanned from a driver to a value that can be
+// encoded in JSON.
+func sqlValToJSON(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil, bool, string, int64, int32, int16, int8, int, uint64, uint32, uint16, uint8, uint:
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return v
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return strconv.FormatFloat(float64(v), 'g', -1, 32)
+		}
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -170,3 +170,36 @@ func (db *SQLite3DBRepository) Exec(ctx context.Context, query string) (sql.Resu
 func (db *SQLite3DBRepository) Query(ctx context.Context, query string) (*sql.Rows, error) {
 	return db.Conn.QueryContext(ctx, query)
 }
+
+func (db *SQLite3DBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	rows, err := explainQueryer(db.Conn, conn).QueryContext(ctx, "EXPLAIN QUERY PLAN "+explainQuery(query))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	// SQLite does not estimate the cost and rows of its plan, the operations
+	// are only linked to their parent
+	var roots []*PlanNode
+	nodes := map[int64]*PlanNode{}
+	for rows.Next() {
+		var (
+			id, parent, notused int64
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			return nil, err
+		}
+		node := &PlanNode{Operation: detail}
+		nodes[id] = node
+		if p, ok := nodes[parent]; ok {
+			p.Children = append(p.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
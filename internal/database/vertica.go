@@ -237,6 +237,10 @@ func (db *VerticaDBRepository) Query(ctx context.Context, query string) (*sql.Ro
 	return db.Conn.QueryContext(ctx, query)
 }
 
+func (db *VerticaDBRepository) Explain(ctx context.Context, conn *sql.Conn, query string) ([]*PlanNode, error) {
+	return nil, ErrNotImplementation
+}
+
 func (db *VerticaDBRepository) DescribeForeignKeysBySchema(ctx context.Context, schemaName string) ([]*ForeignKey, error) {
 	return nil, fmt.Errorf("describe foreign keys is not supported")
 }
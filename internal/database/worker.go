@@ -4,40 +4,83 @@ import (
 	"context"
 	"log"
 	"sync"
+	"sync/atomic"
 )
 
+// CacheRef refers to the cache of a database. An update replaces the cache
+// instead of modifying it, so that the cache returned by Load can be read
+// without locking while it is refreshed.
+type CacheRef struct {
+	cache atomic.Pointer[DBCache]
+}
+
+// Load returns the current cache, or nil if r is nil.
+func (r *CacheRef) Load() *DBCache {
+	if r == nil {
+		return nil
+	}
+	return r.cache.Load()
+}
+
+// setColumns replaces the cache with a copy having col as its columns.
+func (r *CacheRef) setColumns(col map[string][]*ColumnDesc) {
+	for {
+		old := r.cache.Load()
+		if old == nil {
+			return
+		}
+		c := *old
+		c.ColumnsWithParent = col
+		if r.cache.CompareAndSwap(old, &c) {
+			return
+		}
+	}
+}
+
 type Worker struct {
-	dbRepo  DBRepository
-	dbCache *DBCache
+	dbRepo   DBRepository
+	dbCache  *CacheRef
+	progress CacheProgressFunc
 
-	done   chan struct{}
-	update chan struct{}
-	lock   sync.Mutex
+	// ctx is cancelled by Stop to abort the secondary cache update
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+	update   chan struct{}
+	// lock guards dbRepo, dbCache and progress
+	lock sync.Mutex
 }
 
 func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Worker{
+		ctx:    ctx,
+		cancel: cancel,
 		done:   make(chan struct{}, 1),
 		update: make(chan struct{}, 1),
 	}
 }
 
+// Cache returns the current cache. It must not be modified, it is shared with
+// the other requests.
 func (w *Worker) Cache() *DBCache {
-	return w.dbCache
+	return w.CacheRef().Load()
 }
 
-func (w *Worker) setCache(c *DBCache) {
+// CacheRef returns the reference to the cache of the current database.
+func (w *Worker) CacheRef() *CacheRef {
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	w.dbCache = c
+	return w.dbCache
 }
 
-func (w *Worker) setColumnCache(col map[string][]*ColumnDesc) {
+func (w *Worker) setCache(c *DBCache) {
+	ref := &CacheRef{}
+	ref.cache.Store(c)
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	if w.dbCache != nil {
-		w.dbCache.ColumnsWithParent = col
-	}
+	w.dbCache = ref
 }
 
 func (w *Worker) Start() {
@@ -49,12 +92,15 @@ func (w *Worker) Start() {
 				log.Println("db worker: done")
 				return
 			case <-w.update:
-				generator := NewDBCacheUpdater(w.dbRepo)
-				col, err := generator.GenerateDBCacheSecondary(context.Background())
+				// The columns are set to the cache of the repository they
+				// were loaded from, even if the database has been switched
+				generator, ref := w.newGenerator("Loading the columns of all schemas")
+				col, err := generator.GenerateDBCacheSecondary(w.ctx)
 				if err != nil {
 					log.Println(err)
+					continue
 				}
-				w.setColumnCache(col)
+				ref.setColumns(col)
 				log.Println("db worker: Update db cache secondary complete")
 			}
 		}
@@ -62,11 +108,19 @@ func (w *Worker) Start() {
 }
 
 func (w *Worker) Stop() {
-	close(w.done)
+	w.stopOnce.Do(func() {
+		w.cancel()
+		close(w.done)
+	})
 }
 
-func (w *Worker) ReCache(ctx context.Context, repo DBRepository) error {
+// ReCache builds the cache of repo. The columns of the other schemas than the
+// current one are loaded in the background. progress may be nil.
+func (w *Worker) ReCache(ctx context.Context, repo DBRepository, progress CacheProgressFunc) error {
+	w.lock.Lock()
 	w.dbRepo = repo
+	w.progress = progress
+	w.lock.Unlock()
 	if err := w.updateAllCache(ctx); err != nil {
 		return err
 	}
@@ -74,8 +128,17 @@ func (w *Worker) ReCache(ctx context.Context, repo DBRepository) error {
 	return nil
 }
 
+// UseCache makes ref, built by another worker for the same database, the
+// cache of repo without loading it again.
+func (w *Worker) UseCache(repo DBRepository, ref *CacheRef) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.dbRepo = repo
+	w.dbCache = ref
+}
+
 func (w *Worker) updateAllCache(ctx context.Context) error {
-	generator := NewDBCacheUpdater(w.dbRepo)
+	generator, _ := w.newGenerator("Loading the database metadata")
 	cache, err := generator.GenerateDBCachePrimary(ctx)
 	if err != nil {
 		return err
@@ -85,6 +148,18 @@ func (w *Worker) updateAllCache(ctx context.Context) error {
 	return nil
 }
 
+// newGenerator returns the generator of the cache of the current repository,
+// and the reference to that cache.
+func (w *Worker) newGenerator(title string) (*DBCacheGenerator, *CacheRef) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	generator := NewDBCacheUpdater(w.dbRepo)
+	if w.progress != nil {
+		generator.SetProgress(w.progress(title))
+	}
+	return generator, w.dbCache
+}
+
 func (w *Worker) updateAdditionalCache() {
 	w.update <- struct{}{}
 }
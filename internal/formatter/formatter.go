@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/sqls-server/sqls/ast"
 	"github.com/sqls-server/sqls/ast/astutil"
@@ -48,6 +49,72 @@ func Format(text string, params lsp.DocumentFormattingParams, cfg *config.Config
 	return res, nil
 }
 
+// FormatRange formats the statements intersecting the range of params. The
+// whitespace and the comments around the statements and the other statements
+// are left as is.
+func FormatRange(text string, params lsp.DocumentRangeFormattingParams, cfg *config.Config) ([]lsp.TextEdit, error) {
+	if text == "" {
+		return nil, errors.New("empty")
+	}
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// The range and the edits are in UTF-16 columns, while the tokens count a
+	// tab as several columns
+	textLines := strings.Split(text, "\n")
+	lineAt := func(line int) string {
+		if line >= 0 && line < len(textLines) {
+			return textLines[line]
+		}
+		return ""
+	}
+	toPosition := func(p token.Pos) lsp.Position {
+		return lsp.Position{Line: p.Line, Character: token.UTF16Col(lineAt(p.Line), p.Col)}
+	}
+	toPos := func(p lsp.Position) token.Pos {
+		return token.Pos{Line: p.Line, Col: token.ColOfUTF16(lineAt(p.Line), p.Character)}
+	}
+	rngStart := toPos(params.Range.Start)
+	rngEnd := toPos(params.Range.End)
+	opts := &ast.RenderOptions{
+		LowerCase: cfg.LowercaseKeywords,
+	}
+	res := []lsp.TextEdit{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		// The comments before the statement are kept verbatim
+		nodes := trimLeadingComments(trimWhitespace(stmt.GetTokens()))
+		if len(nodes) == 0 {
+			continue
+		}
+		st := nodes[0].Pos()
+		en := nodes[len(nodes)-1].End()
+		if token.ComparePos(st, rngEnd) > 0 || token.ComparePos(rngStart, en) > 0 {
+			continue
+		}
+
+		env := &formatEnvironment{
+			options: params.Options,
+		}
+		formatted := Eval(&ast.Statement{Toks: nodes}, env)
+		// The line break after a semicolon belongs to the untouched text
+		newText := strings.TrimRight(formatted.Render(opts), " \t\n")
+		res = append(res, lsp.TextEdit{
+			Range: lsp.Range{
+				Start: toPosition(st),
+				End:   toPosition(en),
+			},
+			NewText: newText,
+		})
+	}
+	return res, nil
+}
+
 type formatEnvironment struct {
 	reader      *astutil.NodeReader
 	indentLevel int
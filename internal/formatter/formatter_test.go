@@ -43,6 +43,218 @@ func TestEval(t *testing.T) {
 	}
 }
 
+func TestFormatRange(t *testing.T) {
+	input := "select  1;\n\n-- second\nselect a  from b   where  a = 1;  \nselect 3\n"
+	rng := func(sl, sc, el, ec int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: sl, Character: sc},
+			End:   lsp.Position{Line: el, Character: ec},
+		}
+	}
+	testcases := []struct {
+		name     string
+		input    string
+		rng      lsp.Range
+		expected string
+	}{
+		{
+			name:     "single statement",
+			rng:      rng(3, 10, 3, 10),
+			expected: "select  1;\n\n-- second\nSELECT\n\ta\nFROM\n\tb\nWHERE\n\ta = 1;  \nselect 3\n",
+		},
+		{
+			name:     "several statements",
+			rng:      rng(0, 3, 4, 2),
+			expected: "SELECT\n\t1;\n\n-- second\nSELECT\n\ta\nFROM\n\tb\nWHERE\n\ta = 1;  \nSELECT\n\t3\n",
+		},
+		{
+			name:     "blank lines",
+			rng:      rng(1, 0, 1, 0),
+			expected: input,
+		},
+		{
+			name:     "leading comments",
+			input:    "SELECT 1; -- keep\n/* c */ select  a from t;",
+			rng:      rng(1, 10, 1, 10),
+			expected: "SELECT 1; -- keep\n/* c */ SELECT\n\ta\nFROM\n\tt;",
+		},
+		{
+			name:     "tab",
+			input:    "SELECT\t1; SELECT 2;",
+			rng:      rng(0, 0, 0, 3),
+			expected: "SELECT\n\t1; SELECT 2;",
+		},
+		{
+			name:     "surrogate pair",
+			input:    "SELECT '😀'; select  2;",
+			rng:      rng(0, 14, 0, 14),
+			expected: "SELECT '😀'; SELECT\n\t2;",
+		},
+		{
+			name:     "comments only",
+			input:    "SELECT 1; -- keep\n/* c */",
+			rng:      rng(1, 2, 1, 2),
+			expected: "SELECT 1; -- keep\n/* c */",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			input := input
+			if tt.input != "" {
+				input = tt.input
+			}
+			params := lsp.DocumentRangeFormattingParams{Range: tt.rng}
+			edits, err := FormatRange(input, params, &config.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := applyTextEdits(input, edits); actual != tt.expected {
+				t.Errorf("expected: %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatOnType(t *testing.T) {
+	tabOptions := lsp.FormattingOptions{}
+	spaceOptions := lsp.FormattingOptions{TabSize: 2, InsertSpaces: true}
+	testcases := []struct {
+		name      string
+		input     string
+		line      int
+		character int
+		ch        string
+		options   lsp.FormattingOptions
+		config    *config.Config
+		expected  string
+	}{
+		{
+			name:     "newline after a clause",
+			input:    "select a\n",
+			line:     1,
+			ch:       "\n",
+			options:  tabOptions,
+			config:   &config.Config{},
+			expected: "SELECT a\n\t",
+		},
+		{
+			name:     "newline outdents the clause keyword",
+			input:    "SELECT a\n  from b\n",
+			line:     2,
+			ch:       "\n",
+			options:  spaceOptions,
+			config:   &config.Config{},
+			expected: "SELECT a\nFROM b\n  ",
+		},
+		{
+			name:      "closing parenthesis",
+			input:     "SELECT *\nFROM (\n\tSELECT 1\n\t)",
+			line:      3,
+			character: 2,
+			ch:        ")",
+			options:   tabOptions,
+			config:    &config.Config{},
+			expected:  "SELECT *\nFROM (\n\tSELECT 1\n)",
+		},
+		{
+			name:     "sub query",
+			input:    "SELECT *\nFROM\n\t(\nselect\n",
+			line:     4,
+			ch:       "\n",
+			options:  tabOptions,
+			config:   &config.Config{},
+			expected: "SELECT *\nFROM\n\t(\n\t\tSELECT\n\t\t\t",
+		},
+		{
+			name:      "semicolon with lowercase keywords",
+			input:     "SELECT 1\n    FROM t;",
+			line:      1,
+			character: 11,
+			ch:        ";",
+			options:   tabOptions,
+			config:    &config.Config{LowercaseKeywords: true},
+			expected:  "SELECT 1\nfrom t;",
+		},
+		{
+			name:      "semicolon after a tab indent",
+			input:     "\tselect 1;",
+			character: 10,
+			ch:        ";",
+			options:   tabOptions,
+			config:    &config.Config{},
+			expected:  "SELECT 1;",
+		},
+		{
+			name:      "semicolon after a tab indented clause",
+			input:     "SELECT 1\n\tfrom\tt;",
+			line:      1,
+			character: 8,
+			ch:        ";",
+			options:   tabOptions,
+			config:    &config.Config{},
+			expected:  "SELECT 1\nFROM\tt;",
+		},
+		{
+			name:     "inside a comment",
+			input:    "SELECT 1 /*\n  x */",
+			line:     1,
+			ch:       "\n",
+			options:  tabOptions,
+			config:   &config.Config{},
+			expected: "SELECT 1 /*\n  x */",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			params := lsp.DocumentOnTypeFormattingParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					Position: lsp.Position{Line: tt.line, Character: tt.character},
+				},
+				Ch:      tt.ch,
+				Options: tt.options,
+			}
+			edits, err := FormatOnType(tt.input, params, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := applyTextEdits(tt.input, edits); actual != tt.expected {
+				t.Errorf("expected: %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// applyTextEdits applies non overlapping edits sorted by position.
+func applyTextEdits(text string, edits []lsp.TextEdit) string {
+	offset := func(pos lsp.Position) int {
+		lines := strings.SplitAfter(text, "\n")
+		n := 0
+		for _, line := range lines[:pos.Line] {
+			n += len(line)
+		}
+		// The character is counted in UTF-16 code units
+		u := 0
+		for i, r := range lines[pos.Line] {
+			if u >= pos.Character {
+				return n + i
+			}
+			if r >= 0x10000 {
+				u += 2
+			} else {
+				u++
+			}
+		}
+		return n + len(lines[pos.Line])
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		start, end := offset(edits[i].Range.Start), offset(edits[i].Range.End)
+		text = text[:start] + edits[i].NewText + text[end:]
+	}
+	return text
+}
+
 func TestRenderIdentifier(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -14,6 +14,38 @@ var whitespaceNode = ast.NewItem(&token.Token{
 	Value: " ",
 })
 
+// trimWhitespace removes the leading and trailing whitespace of nodes.
+func trimWhitespace(nodes []ast.Node) []ast.Node {
+	isWhitespace := func(node ast.Node) bool {
+		tok, ok := node.(ast.Token)
+		return ok && tok.GetToken().MatchKind(token.Whitespace)
+	}
+	for len(nodes) > 0 && isWhitespace(nodes[0]) {
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 && isWhitespace(nodes[len(nodes)-1]) {
+		nodes = nodes[:len(nodes)-1]
+	}
+	return nodes
+}
+
+// trimLeadingComments removes the comments and the whitespace before the
+// first token of nodes.
+func trimLeadingComments(nodes []ast.Node) []ast.Node {
+	isComment := func(node ast.Node) bool {
+		tok, ok := node.(ast.Token)
+		if !ok {
+			return false
+		}
+		t := tok.GetToken()
+		return t.MatchKind(token.Whitespace) || t.MatchKind(token.Comment) || t.MatchKind(token.MultilineComment)
+	}
+	for len(nodes) > 0 && isComment(nodes[0]) {
+		nodes = nodes[1:]
+	}
+	return nodes
+}
+
 func whiteSpaceNodes(num int) []ast.Node {
 	res := make([]ast.Node, num)
 	for i := 0; i < num; i++ {
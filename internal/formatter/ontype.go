@@ -0,0 +1,231 @@
+package formatter
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/token"
+)
+
+// clauseKeywords start the clauses that Eval puts on their own line. The
+// body of a clause is indented one level deeper than its keyword.
+var clauseKeywords = map[string]bool{
+	"SELECT":    true,
+	"FROM":      true,
+	"WHERE":     true,
+	"GROUP":     true,
+	"ORDER":     true,
+	"HAVING":    true,
+	"LIMIT":     true,
+	"OFFSET":    true,
+	"UNION":     true,
+	"EXCEPT":    true,
+	"INTERSECT": true,
+	"VALUES":    true,
+	"SET":       true,
+	"JOIN":      true,
+	"INNER":     true,
+	"LEFT":      true,
+	"RIGHT":     true,
+	"FULL":      true,
+	"CROSS":     true,
+	"INSERT":    true,
+	"UPDATE":    true,
+	"DELETE":    true,
+	"WITH":      true,
+	"RETURNING": true,
+}
+
+// FormatOnType re-indents the line of the typed character and fixes the case
+// of the last keyword before it. A newline re-indents the line that it ended
+// as well as the new line.
+func FormatOnType(text string, params lsp.DocumentOnTypeFormattingParams, cfg *config.Config) ([]lsp.TextEdit, error) {
+	if text == "" {
+		return nil, errors.New("empty")
+	}
+	tokens := tokenizeUntilError(text)
+
+	textLines := strings.Split(text, "\n")
+	lines := []int{params.Position.Line}
+	if params.Ch == "\n" && params.Position.Line > 0 {
+		lines = []int{params.Position.Line - 1, params.Position.Line}
+	}
+
+	res := []lsp.TextEdit{}
+	for _, line := range lines {
+		if line >= len(textLines) || inMultilineToken(tokens, line) {
+			continue
+		}
+		env := &formatEnvironment{
+			indentLevel: indentLevelAt(tokens, line),
+			options:     params.Options,
+		}
+		indent := ""
+		for _, node := range env.genIndent() {
+			indent += node.String()
+		}
+		current := textLines[line][:len(textLines[line])-len(strings.TrimLeft(textLines[line], " \t"))]
+		if current == indent {
+			continue
+		}
+		res = append(res, lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: line, Character: 0},
+				End:   lsp.Position{Line: line, Character: len(current)},
+			},
+			NewText: indent,
+		})
+	}
+	// An indent inserted at the start of the keyword goes first
+	if edit := keywordCaseEdit(tokens, textLines, params.Position, lines[0], cfg); edit != nil {
+		res = append(res, *edit)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a := token.Pos{Line: res[i].Range.Start.Line, Col: res[i].Range.Start.Character}
+		b := token.Pos{Line: res[j].Range.Start.Line, Col: res[j].Range.Start.Character}
+		return token.ComparePos(a, b) < 0
+	})
+	return res, nil
+}
+
+func tokenizeUntilError(text string) []*token.Token {
+	tokens := []*token.Token{}
+	tokenizer := token.NewTokenizer(strings.NewReader(text), &dialect.GenericSQLDialect{})
+	for {
+		tok, err := tokenizer.NextToken()
+		if err != nil {
+			// io.EOF or the first error, such as an unclosed comment
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// keywordCaseEdit renders the last keyword ending at or before pos in the
+// configured case, if it starts on fromLine or later. pos is in the UTF-16
+// columns of textLines, and the edit too, while the tokens count a tab as
+// several columns.
+func keywordCaseEdit(tokens []*token.Token, textLines []string, pos lsp.Position, fromLine int, cfg *config.Config) *lsp.TextEdit {
+	toPosition := func(p token.Pos) lsp.Position {
+		line := ""
+		if p.Line < len(textLines) {
+			line = textLines[p.Line]
+		}
+		return lsp.Position{Line: p.Line, Character: token.UTF16Col(line, p.Col)}
+	}
+	var last *token.Token
+	for _, tok := range tokens {
+		to := toPosition(tok.To)
+		if to.Line > pos.Line || (to.Line == pos.Line && to.Character > pos.Character) {
+			break
+		}
+		if word, ok := tok.Value.(*token.SQLWord); ok && word.QuoteStyle == 0 && word.Kind != dialect.Unmatched {
+			last = tok
+		}
+	}
+	if last == nil || last.From.Line < fromLine {
+		return nil
+	}
+	rendered := ast.NewItem(last).Render(&ast.RenderOptions{LowerCase: cfg.LowercaseKeywords})
+	if rendered == last.Value.(*token.SQLWord).String() {
+		return nil
+	}
+	return &lsp.TextEdit{
+		Range: lsp.Range{
+			Start: toPosition(last.From),
+			End:   toPosition(last.To),
+		},
+		NewText: rendered,
+	}
+}
+
+// inMultilineToken reports whether line starts inside a comment or a string
+// spanning several lines, whose indent is part of its text.
+func inMultilineToken(tokens []*token.Token, line int) bool {
+	for _, tok := range tokens {
+		if tok.Kind != token.Whitespace && tok.From.Line < line && tok.To.Line >= line {
+			return true
+		}
+	}
+	return false
+}
+
+type indentFrame struct {
+	// level is the indent level of the clause keywords
+	level int
+	// parenLevel is the indent level of the line opening the parenthesis
+	parenLevel int
+	inBody     bool
+}
+
+func (f *indentFrame) bodyLevel() int {
+	if f.inBody {
+		return f.level + 1
+	}
+	return f.level
+}
+
+// indentLevelAt returns the indent level that Eval gives to line. Clause
+// keywords are at the level of their statement or sub query, the clause
+// bodies one level deeper, and the contents of parentheses one level deeper
+// than the line opening them.
+func indentLevelAt(tokens []*token.Token, line int) int {
+	stack := []*indentFrame{{}}
+	levelOf := func(first *token.Token) int {
+		top := stack[len(stack)-1]
+		if first == nil {
+			return top.bodyLevel()
+		}
+		if first.Kind == token.RParen && len(stack) > 1 {
+			return top.parenLevel
+		}
+		if isClauseKeyword(first) {
+			return top.level
+		}
+		return top.bodyLevel()
+	}
+
+	lineLevel, lastLine := 0, -1
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case token.Whitespace, token.Comment, token.MultilineComment:
+			continue
+		}
+		if tok.From.Line > line {
+			break
+		}
+		if tok.From.Line != lastLine {
+			lastLine = tok.From.Line
+			lineLevel = levelOf(tok)
+			if lastLine == line {
+				return lineLevel
+			}
+		}
+
+		top := stack[len(stack)-1]
+		switch {
+		case tok.Kind == token.Semicolon:
+			stack = []*indentFrame{{}}
+		case tok.Kind == token.LParen:
+			stack = append(stack, &indentFrame{level: lineLevel + 1, parenLevel: lineLevel})
+		case tok.Kind == token.RParen:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case isClauseKeyword(tok):
+			top.inBody = true
+		}
+	}
+	return levelOf(nil)
+}
+
+func isClauseKeyword(tok *token.Token) bool {
+	word, ok := tok.Value.(*token.SQLWord)
+	return ok && tok.Kind == token.SQLKeyword && word.QuoteStyle == 0 && clauseKeywords[word.Keyword]
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// Handler returns the jsonrpc2 handler of the server. Requests are handled in
+// the order they are read, except the long running ones, which are handled in
+// their own goroutine so that a slow query does not hold up completion, hover
+// or the $/cancelRequest cancelling it.
+func (s *Server) Handler() jsonrpc2.Handler {
+	return &serverHandler{
+		server: s,
+		h:      jsonrpc2.HandlerWithError(s.Handle),
+	}
+}
+
+type serverHandler struct {
+	server *Server
+	h      jsonrpc2.Handler
+}
+
+func (sh *serverHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	// The request is tracked before it is handed off, so that a cancel read
+	// right after it finds it
+	ctx, done := sh.server.trackRequest(ctx, req)
+	if isLongRunning(req) {
+		go func() {
+			defer done()
+			sh.h.Handle(ctx, conn, req)
+		}()
+		return
+	}
+	defer done()
+	sh.h.Handle(ctx, conn, req)
+}
+
+// isLongRunning reports whether req may take as long as the database makes it.
+func isLongRunning(req *jsonrpc2.Request) bool {
+	return req.Method == "workspace/executeCommand"
+}
+
+// trackRequest derives a context of req that $/cancelRequest cancels. done
+// must be called once the request is handled.
+func (s *Server) trackRequest(ctx context.Context, req *jsonrpc2.Request) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if req.Notif {
+		return ctx, cancel
+	}
+
+	s.requestsMu.Lock()
+	s.requests[req.ID] = cancel
+	s.requestsMu.Unlock()
+	return ctx, func() {
+		s.requestsMu.Lock()
+		delete(s.requests, req.ID)
+		s.requestsMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRequests cancels all requests in flight and the background tasks.
+func (s *Server) cancelRequests() {
+	s.cancel()
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	for _, cancel := range s.requests {
+		cancel()
+	}
+}
+
+func (s *Server) handleCancelRequest(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CancelParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	// The request may have already been answered
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	if cancel, ok := s.requests[params.ID]; ok {
+		cancel()
+	}
+	return nil, nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// blockingDriver is a mock database whose statements run until they are
+// cancelled.
+const blockingDriver = "mock-blocking"
+
+var blockingExecStarted = make(chan struct{}, 1)
+
+func init() {
+	database.RegisterOpen(blockingDriver, func(connCfg *database.DBConfig) (*database.DBConnection, error) {
+		return &database.DBConnection{}, nil
+	})
+	database.RegisterFactory(blockingDriver, func(db *sql.DB) database.DBRepository {
+		repo := database.NewMockDBRepository(db).(*database.MockDBRepository)
+		repo.MockExec = func(ctx context.Context, query string) (sql.Result, error) {
+			blockingExecStarted <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return repo
+	})
+}
+
+func TestCancelRequest(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: blockingDriver},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "INSERT INTO city VALUES (1);\nSELECT  FROM city")
+
+	id := jsonrpc2.ID{Num: 1000}
+	executeCommandParams := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI},
+	}
+	errc := make(chan error, 1)
+	go func() {
+		var got interface{}
+		errc <- tx.conn.Call(tx.ctx, "workspace/executeCommand", executeCommandParams, &got, jsonrpc2.PickID(id))
+	}()
+
+	select {
+	case <-blockingExecStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not executed")
+	}
+
+	// Completion is served while the query runs
+	completionParams := lsp.CompletionParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{
+				URI: testFileURI,
+			},
+			Position: lsp.Position{
+				Line:      1,
+				Character: 7,
+			},
+		},
+	}
+	var items []lsp.CompletionItem
+	if err := tx.conn.Call(tx.ctx, "textDocument/completion", completionParams, &items); err != nil {
+		t.Fatal("conn.Call textDocument/completion:", err)
+	}
+	if len(items) == 0 {
+		t.Error("no completion items while the query runs")
+	}
+
+	if err := tx.conn.Notify(tx.ctx, "$/cancelRequest", lsp.CancelParams{ID: id}); err != nil {
+		t.Fatal("conn.Notify $/cancelRequest:", err)
+	}
+	select {
+	case err := <-errc:
+		var rpcErr *jsonrpc2.Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != lsp.CodeRequestCancelled {
+			t.Errorf("unexpected error of the cancelled request: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not cancelled")
+	}
+
+	// Cancelling a request that has been answered is a no-op
+	if err := tx.conn.Notify(tx.ctx, "$/cancelRequest", lsp.CancelParams{ID: id}); err != nil {
+		t.Fatal("conn.Notify $/cancelRequest:", err)
+	}
+	var got interface{}
+	if err := tx.conn.Call(tx.ctx, "textDocument/completion", completionParams, &got); err != nil {
+		t.Fatal("conn.Call textDocument/completion:", err)
+	}
+}
+
+func TestCancelQuery(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: blockingDriver},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "INSERT INTO city VALUES (1);\nINSERT INTO city VALUES (2);")
+
+	executeCommandParams := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI, "-output=json"},
+	}
+	type response struct {
+		results []*QueryResult
+		err     error
+	}
+	resc := make(chan response, 1)
+	go func() {
+		var got []*QueryResult
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", executeCommandParams, &got)
+		resc <- response{got, err}
+	}()
+
+	select {
+	case <-blockingExecStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not executed")
+	}
+
+	cancelParams := lsp.ExecuteCommandParams{Command: CommandCancelQuery}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", cancelParams, nil); err != nil {
+		t.Fatal("conn.Call cancelQuery:", err)
+	}
+	select {
+	case res := <-resc:
+		if res.err != nil {
+			t.Fatal("conn.Call executeQuery:", res.err)
+		}
+		// The statements after the cancelled one are not executed
+		if len(res.results) != 1 || res.results[0].Error != errQueryCancelled.Error() {
+			t.Errorf("unexpected results of the cancelled query: %+v", res.results)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not cancelled")
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentCodeAction(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CodeActionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	s.cfgMu.RLock()
+	literals := s.codeActionLiterals
+	s.cfgMu.RUnlock()
+	if !literals {
+		// The quick fixes are edits, which a command can not carry
+		return sourceCommands(params.TextDocument.URI), nil
+	}
+
+	actions := []lsp.CodeAction{}
+	if f, ok := s.file(params.TextDocument.URI); ok {
+		actions = append(actions, quickFixes(params.TextDocument.URI, f, params.Range, s.worker.Cache())...)
+	}
+	actions = append(actions, sourceActions(params.TextDocument.URI)...)
+	return filterCodeActions(actions, params.Context.Only), nil
+}
+
+// quickFixes returns the fixes of the schema problems intersecting rng. A fix
+// is preferred when it is the only one of its problem.
+func quickFixes(uri string, f *File, rng lsp.Range, dbCache *database.DBCache) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	if dbCache == nil {
+		return actions
+	}
+	for _, problem := range schemaProblems(f.Text, dbCache) {
+		if !rangesIntersect(problem.diag.Range, rng) {
+			continue
+		}
+		for _, fix := range problem.fixes {
+			actions = append(actions, lsp.CodeAction{
+				Title:       fix.title,
+				Kind:        lsp.CAKQuickFix,
+				Diagnostics: []lsp.Diagnostic{problem.diag},
+				IsPreferred: len(problem.fixes) == 1,
+				Edit: &lsp.WorkspaceEdit{
+					DocumentChanges: []lsp.TextDocumentEdit{
+						{
+							TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+								Version: int32(f.Version),
+								TextDocumentIdentifier: lsp.TextDocumentIdentifier{
+									URI: uri,
+								},
+							},
+							Edits: fix.edits,
+						},
+					},
+				},
+			})
+		}
+	}
+	return actions
+}
+
+// sourceCommands returns the commands that do not depend on the cursor.
+func sourceCommands(uri string) []lsp.Command {
+	return []lsp.Command{
+		{
+			Title:     "Execute Query",
+			Command:   CommandExecuteQuery,
+			Arguments: []interface{}{uri},
+		},
+		{
+			Title:     "Show Databases",
+			Command:   CommandShowDatabases,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Show Schemas",
+			Command:   CommandShowSchemas,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Show Connections",
+			Command:   CommandShowConnections,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Switch Database",
+			Command:   CommandSwitchDatabase,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Switch Connections",
+			Command:   CommandSwitchConnection,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Show Tables",
+			Command:   CommandShowTables,
+			Arguments: []interface{}{},
+		},
+	}
+}
+
+// sourceActions returns the commands of sourceCommands as source actions.
+func sourceActions(uri string) []lsp.CodeAction {
+	commands := sourceCommands(uri)
+	actions := make([]lsp.CodeAction, len(commands))
+	for i := range commands {
+		actions[i] = lsp.CodeAction{
+			Title:   commands[i].Title,
+			Kind:    lsp.CAKSource,
+			Command: &commands[i],
+		}
+	}
+	return actions
+}
+
+// filterCodeActions keeps the actions whose kind is one of only or a sub kind
+// of one of them. An empty only keeps every action.
+func filterCodeActions(actions []lsp.CodeAction, only []lsp.CodeActionKind) []lsp.CodeAction {
+	if len(only) == 0 {
+		return actions
+	}
+	res := []lsp.CodeAction{}
+	for _, action := range actions {
+		for _, kind := range only {
+			if action.Kind == kind || strings.HasPrefix(string(action.Kind), string(kind)+".") {
+				res = append(res, action)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func rangesIntersect(a, b lsp.Range) bool {
+	aStart := token.Pos{Line: a.Start.Line, Col: a.Start.Character}
+	aEnd := token.Pos{Line: a.End.Line, Col: a.End.Character}
+	bStart := token.Pos{Line: b.Start.Line, Col: b.Start.Character}
+	bEnd := token.Pos{Line: b.End.Line, Col: b.End.Character}
+	return token.ComparePos(aStart, bEnd) <= 0 && token.ComparePos(bStart, aEnd) <= 0
+}
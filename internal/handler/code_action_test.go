@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestCodeAction(t *testing.T) {
+	tx := newTestContext()
+	tx.capabilities.TextDocument.CodeAction.CodeActionLiteralSupport = &lsp.CodeActionLiteralSupport{}
+	tx.initServer(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	rng := func(sl, sc, el, ec int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: sl, Character: sc},
+			End:   lsp.Position{Line: el, Character: ec},
+		}
+	}
+	quickFix := []lsp.CodeActionKind{lsp.CAKQuickFix}
+
+	tests := []struct {
+		name  string
+		input string
+		rng   lsp.Range
+		only  []lsp.CodeActionKind
+		want  []string
+	}{
+		{
+			name:  "unknown table",
+			input: "SELECT * FROM cty",
+			rng:   rng(0, 15, 0, 15),
+			only:  quickFix,
+			want: []string{
+				"quickfix Did you mean `city`? (preferred) [0:14-0:17 city]",
+			},
+		},
+		{
+			name:  "unknown column",
+			input: "SELECT ci.Nme FROM city ci",
+			rng:   rng(0, 10, 0, 13),
+			only:  quickFix,
+			want: []string{
+				"quickfix Did you mean `Name`? (preferred) [0:10-0:13 Name]",
+			},
+		},
+		{
+			name:  "ambiguous column",
+			input: "SELECT Name FROM city JOIN country co ON city.CountryCode = co.Code",
+			rng:   rng(0, 7, 0, 7),
+			only:  quickFix,
+			want: []string{
+				"quickfix Qualify as `city.Name` [0:7-0:11 city.Name]",
+				"quickfix Qualify as `co.Name` [0:7-0:11 co.Name]",
+			},
+		},
+		{
+			name:  "missing alias",
+			input: "SELECT c.Name FROM city",
+			rng:   rng(0, 7, 0, 8),
+			only:  quickFix,
+			want: []string{
+				"quickfix Add alias `c` to `city` (preferred) [0:19-0:23 city c]",
+			},
+		},
+		{
+			name:  "unknown table after tabs",
+			input: "SELECT *\tFROM\tcty",
+			rng:   rng(0, 15, 0, 15),
+			only:  quickFix,
+			want: []string{
+				"quickfix Did you mean `city`? (preferred) [0:14-0:17 city]",
+			},
+		},
+		{
+			name:  "missing alias after a tab",
+			input: "SELECT\tc.Name FROM\tcity",
+			rng:   rng(0, 7, 0, 8),
+			only:  quickFix,
+			want: []string{
+				"quickfix Add alias `c` to `city` (preferred) [0:19-0:23 city c]",
+			},
+		},
+		{
+			name:  "outside of the range",
+			input: "SELECT * FROM cty",
+			rng:   rng(0, 0, 0, 6),
+			only:  quickFix,
+			want:  []string{},
+		},
+		{
+			name:  "source actions",
+			input: "SELECT * FROM cty",
+			rng:   rng(0, 15, 0, 15),
+			only:  []lsp.CodeActionKind{lsp.CAKSource},
+			want: []string{
+				"source Execute Query executeQuery",
+				"source Show Databases showDatabases",
+				"source Show Schemas showSchemas",
+				"source Show Connections showConnections",
+				"source Switch Database switchDatabase",
+				"source Switch Connections switchConnections",
+				"source Show Tables showTables",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.CodeActionParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+				Range: tt.rng,
+				Context: lsp.CodeActionContext{
+					Only: tt.only,
+				},
+			}
+			var got []lsp.CodeAction
+			if err := tx.conn.Call(tx.ctx, "textDocument/codeAction", params, &got); err != nil {
+				t.Fatal("conn.Call textDocument/codeAction:", err)
+			}
+
+			res := []string{}
+			for _, action := range got {
+				res = append(res, formatCodeAction(action))
+			}
+			if diff := cmp.Diff(tt.want, res); diff != "" {
+				t.Errorf("unmatch code actions (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodeActionCommands(t *testing.T) {
+	tx := newTestContext()
+	tx.initServer(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT * FROM cty")
+
+	// The client without CodeAction literal support gets the commands
+	params := lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+		Range: lsp.Range{
+			Start: lsp.Position{Line: 0, Character: 15},
+			End:   lsp.Position{Line: 0, Character: 15},
+		},
+	}
+	var got []lsp.Command
+	if err := tx.conn.Call(tx.ctx, "textDocument/codeAction", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/codeAction:", err)
+	}
+	res := []string{}
+	for _, command := range got {
+		res = append(res, command.Command)
+	}
+	want := []string{
+		CommandExecuteQuery,
+		CommandShowDatabases,
+		CommandShowSchemas,
+		CommandShowConnections,
+		CommandSwitchDatabase,
+		CommandSwitchConnection,
+		CommandShowTables,
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("unmatch commands (- want, + got):\n%s", diff)
+	}
+	if got[0].Arguments[0] != testFileURI {
+		t.Errorf("unexpected arguments of executeQuery %v", got[0].Arguments)
+	}
+}
+
+func formatCodeAction(action lsp.CodeAction) string {
+	s := fmt.Sprintf("%s %s", action.Kind, action.Title)
+	if action.IsPreferred {
+		s += " (preferred)"
+	}
+	if action.Command != nil {
+		s += " " + action.Command.Command
+	}
+	if action.Edit != nil {
+		edits := []string{}
+		for _, change := range action.Edit.DocumentChanges {
+			for _, edit := range change.Edits {
+				r := edit.Range
+				edits = append(edits, fmt.Sprintf("%d:%d-%d:%d %s", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character, edit.NewText))
+			}
+		}
+		s += fmt.Sprintf(" %v", edits)
+	}
+	return s
+}
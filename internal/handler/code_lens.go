@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func (s *Server) handleTextDocumentCodeLens(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CodeLensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return codeLenses(params.TextDocument.URI, f.Text)
+}
+
+// codeLenses returns the lenses running each statement of the document with
+// executeQuery, and explaining it with explainQuery.
+func codeLenses(uri, text string) ([]lsp.CodeLens, error) {
+	stmts, err := getStatements(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(text)
+	lenses := []lsp.CodeLens{}
+	for _, stmt := range stmts {
+		nodes := statementNodes(stmt)
+		if len(nodes) == 0 {
+			continue
+		}
+		rng := lines.posRange(nodes[0].Pos(), stmt.End())
+		lenses = append(lenses,
+			lsp.CodeLens{
+				Range: rng,
+				Command: &lsp.Command{
+					Title:     "Run",
+					Command:   CommandExecuteQuery,
+					Arguments: []interface{}{uri, rng},
+				},
+			},
+			lsp.CodeLens{
+				Range: rng,
+				Command: &lsp.Command{
+					Title:     "Run (vertical)",
+					Command:   CommandExecuteQuery,
+					Arguments: []interface{}{uri, "-show-vertical", rng},
+				},
+			},
+			lsp.CodeLens{
+				Range: rng,
+				Command: &lsp.Command{
+					Title:     "Explain",
+					Command:   CommandExplainQuery,
+					Arguments: []interface{}{uri, rng},
+				},
+			},
+		)
+	}
+	return lenses, nil
+}
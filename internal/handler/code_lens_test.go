@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestCodeLens(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	input := "SELECT 1;\n\n-- comment\nSELECT *\nFROM city;\n"
+	tx.textDocumentDidOpen(t, testFileURI, input)
+
+	params := lsp.CodeLensParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+	}
+	var got []lsp.CodeLens
+	if err := tx.conn.Call(tx.ctx, "textDocument/codeLens", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/codeLens:", err)
+	}
+
+	gotStrs := []string{}
+	for _, lens := range got {
+		args, err := parseExecuteQueryArgs(lsp.ExecuteCommandParams{
+			Command:   lens.Command.Command,
+			Arguments: lens.Command.Arguments,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStrs = append(gotStrs, fmt.Sprintf("%s %s %s vertical=%t %s",
+			formatRange(lens.Range), lens.Command.Title, lens.Command.Command, args.showVertical, formatRange(*args.rng)))
+	}
+	want := []string{
+		"[0:0-0:9] Run executeQuery vertical=false [0:0-0:9]",
+		"[0:0-0:9] Run (vertical) executeQuery vertical=true [0:0-0:9]",
+		"[0:0-0:9] Explain explainQuery vertical=false [0:0-0:9]",
+		"[3:0-4:10] Run executeQuery vertical=false [3:0-4:10]",
+		"[3:0-4:10] Run (vertical) executeQuery vertical=true [3:0-4:10]",
+		"[3:0-4:10] Explain explainQuery vertical=false [3:0-4:10]",
+	}
+	if diff := cmp.Diff(want, gotStrs); diff != "" {
+		t.Errorf("unmatch code lenses (- want, + got):\n%s", diff)
+	}
+	if got := extractRangeText(input, 3, 0, 4, 10); got != "SELECT *\nFROM city;" {
+		t.Errorf("unmatch statement text: %q", got)
+	}
+}
+
+func TestCodeLensTabs(t *testing.T) {
+	input := "SELECT\t1 AS x;\n\tSELECT 'é'\t;"
+	lenses, err := codeLenses(testFileURI, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, lens := range lenses {
+		if lens.Command.Title != "Run" {
+			continue
+		}
+		r := lens.Range
+		text := extractRangeText(input, r.Start.Line, r.Start.Character, r.End.Line, r.End.Character)
+		got = append(got, fmt.Sprintf("%s %q", formatRange(r), text))
+	}
+	want := []string{
+		`[0:0-0:14] "SELECT\t1 AS x;"`,
+		`[1:1-1:13] "SELECT 'é'\t;"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch code lenses (- want, + got):\n%s", diff)
+	}
+}
@@ -20,17 +20,13 @@ func (s *Server) handleTextDocumentCompletion(ctx context.Context, conn *jsonrpc
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.file(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
 	c := completer.NewCompleter(s.worker.Cache())
-	if s.dbConn != nil {
-		c.Driver = s.dbConn.Driver
-	} else {
-		c.Driver = ""
-	}
+	c.Driver = s.driver()
 	completionItems, err := c.Complete(f.Text, params, s.getConfig().LowercaseKeywords)
 	if err != nil {
 		return nil, err
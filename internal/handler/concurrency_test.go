@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// TestConcurrentRequests hammers completion, hover and document changes
+// while the database cache is refreshed. The handlers are called directly
+// from goroutines, as the connection dispatches the requests other than the
+// commands one at a time. Run it with -race.
+func TestConcurrentRequests(t *testing.T) {
+	tx := newTestContext()
+	tx.server.Caches = database.NewCacheStore()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{{Driver: "mock"}},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT  FROM city")
+
+	call := func(method string, params interface{}) error {
+		req := &jsonrpc2.Request{Method: method}
+		if err := req.SetParams(params); err != nil {
+			return err
+		}
+		_, err := tx.server.Handle(tx.ctx, tx.connServer, req)
+		return err
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errc := make(chan error, 4*n)
+
+	// Refresh the cache, switching between databases sharing the cache store
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			params := lsp.ExecuteCommandParams{
+				Command:   CommandSwitchDatabase,
+				Arguments: []interface{}{fmt.Sprintf("world%d", i%3)},
+			}
+			if err := call("workspace/executeCommand", params); err != nil {
+				errc <- fmt.Errorf("switchDatabase: %w", err)
+			}
+		}
+	}()
+
+	// Change the document
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= n; i++ {
+			params := lsp.DidChangeTextDocumentParams{
+				TextDocument: lsp.VersionedTextDocumentIdentifier{
+					URI:     testFileURI,
+					Version: i,
+				},
+				ContentChanges: []lsp.TextDocumentContentChangeEvent{
+					{Text: fmt.Sprintf("SELECT  FROM city WHERE ID = %d", i)},
+				},
+			}
+			if err := call("textDocument/didChange", params); err != nil {
+				errc <- fmt.Errorf("didChange: %w", err)
+			}
+		}
+	}()
+
+	// Complete and hover
+	position := lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+		Position:     lsp.Position{Line: 0, Character: 7},
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if err := call("textDocument/completion", lsp.CompletionParams{TextDocumentPositionParams: position}); err != nil {
+				errc <- fmt.Errorf("completion: %w", err)
+			}
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			hoverParams := lsp.HoverParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					TextDocument: position.TextDocument,
+					Position:     lsp.Position{Line: 0, Character: 15},
+				},
+			}
+			if err := call("textDocument/hover", hoverParams); err != nil {
+				errc <- fmt.Errorf("hover: %w", err)
+			}
+		}
+	}()
+
+	// Read the cache directly, as the other servers of a daemon do
+	for i := 0; i < n; i++ {
+		if cache := tx.server.worker.Cache(); cache != nil {
+			cache.SortedTables()
+			cache.ColumnDescs("city")
+		}
+	}
+
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// cursorTimeout is how long the rows left by a truncated query are kept for
+// fetchNextPage.
+const cursorTimeout = 5 * time.Minute
+
+// queryCursor is a query truncated by the max rows, whose rows left are
+// fetched a page at a time with fetchNextPage. It holds a database
+// connection until it is closed.
+type queryCursor struct {
+	id string
+	// res is the result of the first page
+	res      *QueryResult
+	rows     *database.RowCursor
+	pageSize int
+	stmt     *statement
+	timer    *time.Timer
+}
+
+func (c *queryCursor) close() {
+	if c == nil {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	// The query is cancelled before its rows are closed, so that they are not
+	// drained, and its connection released after
+	c.stmt.cancel(context.Canceled)
+	c.rows.Close()
+	c.stmt.close()
+}
+
+// keepCursor makes c the cursor of fetchNextPage, closing the previous one.
+// The cursor is closed if it is not used for cursorTimeout. It must be called
+// with connMu held.
+func (s *Server) keepCursor(c *queryCursor) {
+	s.closeCursor()
+	s.cursorSeq++
+	c.id = strconv.Itoa(s.cursorSeq)
+	c.res.Cursor = c.id
+	c.timer = time.AfterFunc(cursorTimeout, func() {
+		s.connMu.Lock()
+		defer s.connMu.Unlock()
+		if s.cursor == c {
+			s.closeCursor()
+		}
+	})
+	s.cursor = c
+}
+
+// closeCursor closes the cursor of fetchNextPage. It must be called with
+// connMu held.
+func (s *Server) closeCursor() {
+	s.cursor.close()
+	s.cursor = nil
+}
+
+func (s *Server) fetchNextPage(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
+	cursorID, args, err := parseFetchNextPageArgs(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolveOutputFile(args); err != nil {
+		return nil, err
+	}
+	c := s.cursor
+	if c == nil || c.id != cursorID {
+		return nil, fmt.Errorf("cursor not found, %q: cursors are closed by the next execution or after %s", cursorID, cursorTimeout)
+	}
+	pageSize := c.pageSize
+	if args.maxRows != nil {
+		pageSize = *args.maxRows
+	}
+
+	start := time.Now()
+	s.startStatement(ctx, c.stmt)
+	typedRows, more, err := c.rows.Fetch(pageSize)
+	s.stopStatement(c.stmt)
+	if err != nil {
+		err = c.stmt.err(err)
+		s.closeCursor()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	res := &QueryResult{
+		Statement: c.res.Statement,
+		Query:     c.res.Query,
+		Columns:   c.res.Columns,
+		Rows:      typedRows,
+		Truncated: more,
+		ElapsedMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if more {
+		res.Cursor = c.id
+		c.timer.Reset(cursorTimeout)
+	} else {
+		s.closeCursor()
+	}
+	return formatResults([]*QueryResult{res}, args, s.driver())
+}
+
+// parseFetchNextPageArgs parses the arguments of fetchNextPage: the cursor of
+// a truncated result, optionally followed by the flags of executeQuery
+// selecting the output and "-max-rows=<n>", the size of the page.
+func parseFetchNextPageArgs(params lsp.ExecuteCommandParams) (string, *executeQueryArgs, error) {
+	if len(params.Arguments) == 0 {
+		return "", nil, fmt.Errorf("required arguments were not provided: <Cursor>")
+	}
+	cursorID, ok := params.Arguments[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("specify the cursor as a string")
+	}
+
+	args := &executeQueryArgs{}
+	for _, arg := range params.Arguments[1:] {
+		if v, ok := arg.(string); ok {
+			if err := args.parseFlag(v); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return cursorID, args, nil
+}
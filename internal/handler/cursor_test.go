@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestFetchNextPage(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         "sqlite3",
+				DataSourceName: "file:fetch_next_page?mode=memory&cache=shared",
+				MaxRows:        4,
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	const query = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT i FROM n;"
+	tx.textDocumentDidOpen(t, testFileURI, query+"\n"+query)
+
+	call := func(command string, args ...interface{}) ([]*QueryResult, error) {
+		params := lsp.ExecuteCommandParams{
+			Command:   command,
+			Arguments: append(args, "-output=json"),
+		}
+		var got []*QueryResult
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got)
+		return got, err
+	}
+	rows := func(from, to int) [][]interface{} {
+		var rows [][]interface{}
+		for i := from; i <= to; i++ {
+			rows = append(rows, []interface{}{float64(i)})
+		}
+		return rows
+	}
+	columns := []*database.Column{{Name: "i", Type: ""}}
+	opts := cmpopts.IgnoreFields(QueryResult{}, "ElapsedMS")
+
+	// Only the last truncated query keeps its rows
+	got, err := call(CommandExecuteQuery, testFileURI)
+	if err != nil {
+		t.Fatal("executeQuery:", err)
+	}
+	want := []*QueryResult{
+		{Statement: 0, Query: query, Columns: columns, Rows: rows(1, 4), Truncated: true},
+		{Statement: 1, Query: query, Columns: columns, Rows: rows(1, 4), Truncated: true, Cursor: "1"},
+	}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unmatch executeQuery (- want, + got):\n%s", diff)
+	}
+
+	got, err = call(CommandFetchNextPage, "1", "-max-rows=3")
+	if err != nil {
+		t.Fatal("fetchNextPage:", err)
+	}
+	want = []*QueryResult{
+		{Statement: 1, Query: query, Columns: columns, Rows: rows(5, 7), Truncated: true, Cursor: "1"},
+	}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unmatch first page (- want, + got):\n%s", diff)
+	}
+
+	got, err = call(CommandFetchNextPage, "1")
+	if err != nil {
+		t.Fatal("fetchNextPage:", err)
+	}
+	want = []*QueryResult{
+		{Statement: 1, Query: query, Columns: columns, Rows: rows(8, 10)},
+	}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unmatch last page (- want, + got):\n%s", diff)
+	}
+
+	if _, err := call(CommandFetchNextPage, "1"); err == nil {
+		t.Error("expected error for the closed cursor")
+	}
+
+	// The max rows of an execution overrides the one of the connection
+	rng := lsp.Range{End: lsp.Position{Line: 0, Character: len(query)}}
+	got, err = call(CommandExecuteQuery, testFileURI, "-max-rows=0", rng)
+	if err != nil {
+		t.Fatal("executeQuery:", err)
+	}
+	want = []*QueryResult{
+		{Statement: 0, Query: query, Columns: columns, Rows: rows(1, 10)},
+	}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unmatch executeQuery without max rows (- want, + got):\n%s", diff)
+	}
+}
@@ -25,7 +25,7 @@ func (s *Server) handleDefinition(ctx context.Context, conn *jsonrpc2.Conn, req
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.file(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
@@ -0,0 +1,672 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+var diagnosticSource = "sqls"
+
+// diagnosticsDelay is how long the diagnostics of a changed document wait for
+// the next change, as checking a large document takes a while.
+const diagnosticsDelay = 300 * time.Millisecond
+
+// scheduleDiagnostics publishes the diagnostics of uri after delay, off the
+// dispatch loop. Scheduling the document again replaces the pending
+// publication.
+func (s *Server) scheduleDiagnostics(conn *jsonrpc2.Conn, uri string, delay time.Duration) {
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	if timer, ok := s.diagnosticsTimers[uri]; ok {
+		timer.Stop()
+	}
+	s.diagnosticsTimers[uri] = time.AfterFunc(delay, func() {
+		if err := s.publishDiagnostics(s.ctx, conn, uri); err != nil {
+			log.Println("publish diagnostics:", err)
+		}
+	})
+}
+
+// republishDiagnostics schedules the diagnostics of the open documents, which
+// depend on the database cache, when the database changes.
+func (s *Server) republishDiagnostics(conn *jsonrpc2.Conn) {
+	for uri := range s.openFiles() {
+		s.scheduleDiagnostics(conn, uri, 0)
+	}
+}
+
+// cancelDiagnostics cancels the pending publication of the diagnostics of
+// uri.
+func (s *Server) cancelDiagnostics(uri string) {
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	if timer, ok := s.diagnosticsTimers[uri]; ok {
+		timer.Stop()
+		delete(s.diagnosticsTimers, uri)
+	}
+}
+
+// publishDiagnostics publishes the diagnostics of the current version of uri.
+// They are dropped if the document changes or is closed while they are
+// computed.
+func (s *Server) publishDiagnostics(ctx context.Context, conn *jsonrpc2.Conn, uri string) error {
+	f, ok := s.file(uri)
+	if !ok {
+		return nil
+	}
+	diags := diagnostics(f.Text, s.worker.Cache())
+
+	// The document is checked again under the lock, so that the
+	// diagnostics of an older version are not published after newer ones
+	s.diagnosticsMu.Lock()
+	defer s.diagnosticsMu.Unlock()
+	if cur, ok := s.file(uri); !ok || cur != f || ctx.Err() != nil {
+		return nil
+	}
+
+	params := lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Version:     f.Version,
+		Diagnostics: diags,
+	}
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", params)
+}
+
+func (s *Server) clearDiagnostics(ctx context.Context, conn *jsonrpc2.Conn, uri string) error {
+	params := lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []lsp.Diagnostic{},
+	}
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", params)
+}
+
+func diagnostics(text string, dbCache *database.DBCache) []lsp.Diagnostic {
+	tokens, diags := tokenizeDiagnostics(text)
+	diags = append(diags, parenthesisDiagnostics(tokens)...)
+	diags = append(diags, statementDiagnostics(tokens)...)
+	lines := newTextLines(text)
+	for i := range diags {
+		diags[i].Range = lines.convertRange(diags[i].Range)
+	}
+	if dbCache != nil {
+		diags = append(diags, schemaDiagnostics(text, dbCache)...)
+	}
+	return diags
+}
+
+// tokenizeDiagnostics tokenizes the text up to the first tokenizer error and
+// reports the error and unterminated string literals.
+func tokenizeDiagnostics(text string) ([]*token.Token, []lsp.Diagnostic) {
+	diags := []lsp.Diagnostic{}
+	tokens := []*token.Token{}
+
+	tokenizer := token.NewTokenizer(strings.NewReader(text), &dialect.GenericSQLDialect{})
+	for {
+		tok, err := tokenizer.NextToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			message := err.Error()
+			if errors.Is(err, token.ErrUnclosedMultilineComment) {
+				message = "unterminated comment"
+			}
+			diags = append(diags, newDiagnostic(tok.From, tok.To, lsp.DSError, message))
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+
+	// An unterminated string literal always runs up to the end of the text
+	if len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		switch last.Kind {
+		case token.SingleQuotedString, token.NationalStringLiteral:
+			// The value of the token does not tell a closing quote from an
+			// escaped one, so the source of the literal is read again
+			start := positionToOffset(text, newTextLines(text).position(last.From))
+			if !stringLiteralClosed(text[start:]) {
+				diags = append(diags, newDiagnostic(last.From, last.To, lsp.DSError, "unterminated string literal"))
+			}
+		}
+	}
+	return tokens, diags
+}
+
+// stringLiteralClosed reports whether the string literal at the start of src,
+// where two quotes escape a quote, has its closing quote.
+func stringLiteralClosed(src string) bool {
+	open := strings.IndexByte(src, '\'')
+	if open < 0 {
+		return false
+	}
+	for i := open + 1; i < len(src); i++ {
+		if src[i] != '\'' {
+			continue
+		}
+		if i+1 < len(src) && src[i+1] == '\'' {
+			i++
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func parenthesisDiagnostics(tokens []*token.Token) []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+	opens := []*token.Token{}
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case token.LParen:
+			opens = append(opens, tok)
+		case token.RParen:
+			if len(opens) == 0 {
+				diags = append(diags, newDiagnostic(tok.From, tok.To, lsp.DSError, "unmatched closing parenthesis"))
+				continue
+			}
+			opens = opens[:len(opens)-1]
+		case token.Semicolon:
+			// Parentheses never span statements
+			for _, open := range opens {
+				diags = append(diags, newDiagnostic(open.From, open.To, lsp.DSError, "unclosed parenthesis"))
+			}
+			opens = opens[:0]
+		}
+	}
+	for _, open := range opens {
+		diags = append(diags, newDiagnostic(open.From, open.To, lsp.DSError, "unclosed parenthesis"))
+	}
+	return diags
+}
+
+func statementDiagnostics(tokens []*token.Token) []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+	isHead := true
+	// depth is the number of BEGIN ... END blocks, such as the bodies of
+	// routines, and CASE ... END the token is in. The statements of the
+	// blocks are procedural, such as RETURN or LEAVE, and are not checked.
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case token.Whitespace, token.Comment, token.MultilineComment:
+			continue
+		case token.Semicolon:
+			isHead = true
+			continue
+		}
+		word, ok := tok.Value.(*token.SQLWord)
+		if !ok || tok.Kind != token.SQLKeyword {
+			isHead = false
+			continue
+		}
+		if isHead && depth == 0 && (word.QuoteStyle != 0 || !database.IsStatementVerb(word.Keyword)) {
+			message := fmt.Sprintf("unknown statement %q", word.String())
+			diags = append(diags, newDiagnostic(tok.From, tok.To, lsp.DSError, message))
+		}
+		isHead = false
+
+		if word.QuoteStyle != 0 {
+			continue
+		}
+		switch word.Keyword {
+		case "BEGIN":
+			// BEGIN also starts a transaction
+			switch nextKeyword(tokens, i) {
+			case "", ";", "TRANSACTION", "TRAN", "WORK", "DEFERRED", "IMMEDIATE", "EXCLUSIVE", "ISOLATION", "READ", "DISTRIBUTED":
+			default:
+				depth++
+			}
+		case "CASE":
+			depth++
+		case "END":
+			// END IF and the ends of loops close blocks that are not counted
+			switch nextKeyword(tokens, i) {
+			case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+			default:
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+	}
+	return diags
+}
+
+// nextKeyword returns the keyword of the token following tokens[i], ";" for
+// a semicolon, or an empty string for other tokens and the end of the text.
+func nextKeyword(tokens []*token.Token, i int) string {
+	for _, tok := range tokens[i+1:] {
+		switch tok.Kind {
+		case token.Whitespace, token.Comment, token.MultilineComment:
+			continue
+		case token.Semicolon:
+			return ";"
+		}
+		if word, ok := tok.Value.(*token.SQLWord); ok && word.QuoteStyle == 0 {
+			return word.Keyword
+		}
+		return ""
+	}
+	return ""
+}
+
+// Codes of the schema diagnostics. Code actions use them to tell the
+// problems apart.
+var (
+	codeUnknownTable     = "unknown-table"
+	codeUnknownColumn    = "unknown-column"
+	codeUnknownQualifier = "unknown-qualifier"
+	codeAmbiguousColumn  = "ambiguous-column"
+)
+
+// pseudoTables are the qualifiers of triggers and upserts that refer to rows
+// rather than to tables of the statement.
+var pseudoTables = map[string]bool{
+	"NEW":      true,
+	"OLD":      true,
+	"EXCLUDED": true,
+	"INSERTED": true,
+	"DELETED":  true,
+}
+
+// schemaProblem is a diagnostic of the schema check together with the edits
+// that may fix it.
+type schemaProblem struct {
+	diag  lsp.Diagnostic
+	fixes []*schemaFix
+}
+
+type schemaFix struct {
+	title string
+	edits []lsp.TextEdit
+}
+
+type resolvedTable struct {
+	db  string
+	tbl string
+}
+
+// schemaDiagnostics reports references to tables and columns that do not
+// exist in the database cache.
+func schemaDiagnostics(text string, dbCache *database.DBCache) []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+	for _, problem := range schemaProblems(text, dbCache) {
+		diags = append(diags, problem.diag)
+	}
+	return diags
+}
+
+// schemaProblems checks the tables, qualifiers and columns of text against
+// the database cache. The problems are sorted by position, and their ranges
+// are in the UTF-16 columns of text.
+func schemaProblems(text string, dbCache *database.DBCache) []*schemaProblem {
+	problems := []*schemaProblem{}
+	stmts, err := getStatements(text)
+	if err != nil {
+		return problems
+	}
+
+	// Objects created in the document are valid in every statement
+	created := map[string]bool{}
+	for _, stmt := range stmts {
+		if info := parseutil.ExtractCreate(stmt); info != nil {
+			created[strings.ToUpper(info.Name)] = true
+		}
+	}
+
+	lines := newTextLines(text)
+	for _, stmt := range stmts {
+		c := &schemaChecker{
+			lines:    lines,
+			ss:       newStatementScopes(stmt),
+			dbCache:  dbCache,
+			created:  created,
+			resolved: map[*parseutil.TableRef]*resolvedTable{},
+		}
+		problems = append(problems, c.check()...)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i].diag.Range.Start, problems[j].diag.Range.Start
+		return token.ComparePos(token.Pos{Line: a.Line, Col: a.Character}, token.Pos{Line: b.Line, Col: b.Character}) < 0
+	})
+	return problems
+}
+
+type schemaChecker struct {
+	lines   textLines
+	ss      *statementScopes
+	dbCache *database.DBCache
+	created map[string]bool
+	// resolved holds the cached tables of the references. Sub queries,
+	// CTEs, created and unknown tables are missing, as their columns can
+	// not be checked.
+	resolved map[*parseutil.TableRef]*resolvedTable
+}
+
+func (c *schemaChecker) check() []*schemaProblem {
+	problems := []*schemaProblem{}
+	refIdents := map[*ast.Identifier]bool{}
+	for _, sc := range c.ss.scopes {
+		for _, ref := range sc.refs {
+			if ref.Ident == nil {
+				continue
+			}
+			refIdents[ref.Ident] = true
+			if problem := c.checkTable(ref); problem != nil {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeMemberIdentifier}}
+	for _, node := range astutil.NewNodeReader(c.ss.stmt).FindRecursive(matcher) {
+		mi, ok := node.(*ast.MemberIdentifier)
+		if !ok || mi.ParentIdent == nil || mi.ChildIdent == nil || refIdents[mi.ChildIdent] {
+			continue
+		}
+		ref := c.ss.resolveQualifier(mi.ParentIdent.NoQuoteString(), c.ss.innermostScope(mi))
+		if ref == nil {
+			if problem := c.checkQualifier(mi); problem != nil {
+				problems = append(problems, problem)
+			}
+			continue
+		}
+		if mi.ChildIdent.IsWildcard() {
+			continue
+		}
+		table, ok := c.resolved[ref]
+		if !ok {
+			continue
+		}
+		cols, ok := c.dbCache.ColumnDatabase(table.db, table.tbl)
+		if !ok {
+			continue
+		}
+		name := mi.ChildIdent.NoQuoteString()
+		if hasColumn(cols, name) {
+			continue
+		}
+		colNames := make([]string, len(cols))
+		for i, col := range cols {
+			colNames[i] = col.Name
+		}
+		message := fmt.Sprintf("column %q does not exist in table %q", name, table.tbl)
+		problems = append(problems, c.newProblem(mi.ChildIdent, codeUnknownColumn, message, c.didYouMean(mi.ChildIdent, colNames)))
+	}
+
+	return append(problems, c.checkAmbiguousColumns()...)
+}
+
+func (c *schemaChecker) checkTable(ref *parseutil.TableRef) *schemaProblem {
+	schema, name := ref.Info.DatabaseSchema, ref.Info.Name
+	if c.created[strings.ToUpper(name)] {
+		return nil
+	}
+	if schema == "" && c.ss.cte(name) != nil {
+		return nil
+	}
+	if _, ok := c.dbCache.SchemaTables[strings.ToUpper(schema)]; schema != "" && !ok {
+		// The schema is not cached, so there is nothing to check against
+		return nil
+	}
+	db, tbl, ok := c.dbCache.Table(schema, name)
+	if ok {
+		c.resolved[ref] = &resolvedTable{db: db, tbl: tbl}
+		return nil
+	}
+
+	tables := c.dbCache.SortedTables()
+	if schema != "" {
+		tables, _ = c.dbCache.SortedTablesByDBName(schema)
+	}
+	message := fmt.Sprintf("table %q does not exist", name)
+	return c.newProblem(ref.Ident, codeUnknownTable, message, c.didYouMean(ref.Ident, tables))
+}
+
+// checkQualifier reports a qualifier that is neither a table nor an alias of
+// the statement. The fixes give the qualifier as an alias to the tables that
+// have none.
+func (c *schemaChecker) checkQualifier(mi *ast.MemberIdentifier) *schemaProblem {
+	qualifier := mi.ParentIdent.NoQuoteString()
+	if pseudoTables[strings.ToUpper(qualifier)] {
+		return nil
+	}
+	if _, ok := c.dbCache.Database(qualifier); ok {
+		return nil
+	}
+	if len(c.resolved) == 0 {
+		// Without known tables the qualifier may be a package or a
+		// sequence, which are not cached
+		return nil
+	}
+
+	fixes := []*schemaFix{}
+	for sc := c.ss.innermostScope(mi); sc != nil; sc = sc.parent {
+		for _, ref := range sc.refs {
+			if ref.Ident == nil {
+				continue
+			}
+			if strings.EqualFold(ref.Info.Name, qualifier) {
+				// Some databases accept the name of an aliased table
+				return nil
+			}
+			// INSERT INTO does not take an alias
+			if ref.AliasIdent != nil || (ref.Ident == c.ss.target && (c.ss.verb == "INSERT" || c.ss.verb == "REPLACE")) {
+				continue
+			}
+			fixes = append(fixes, &schemaFix{
+				title: fmt.Sprintf("Add alias `%s` to `%s`", qualifier, ref.Info.Name),
+				edits: []lsp.TextEdit{{
+					Range:   c.lines.nodeRange(ref.Node),
+					NewText: ref.Node.String() + " " + mi.ParentIdent.String(),
+				}},
+			})
+		}
+	}
+	message := fmt.Sprintf("table or alias %q is not defined", qualifier)
+	return c.newProblem(mi.ParentIdent, codeUnknownQualifier, message, fixes)
+}
+
+// checkAmbiguousColumns reports unqualified columns that exist in more than
+// one table of the same scope.
+func (c *schemaChecker) checkAmbiguousColumns() []*schemaProblem {
+	problems := []*schemaProblem{}
+	if c.ss.create != nil {
+		return problems
+	}
+	usingMatcher := astutil.NodeMatcher{ExpectKeyword: []string{"USING"}}
+	if len(astutil.NewNodeReader(c.ss.stmt).FindRecursive(usingMatcher)) > 0 {
+		// Columns joined with USING are not ambiguous
+		return problems
+	}
+
+	// Column aliases can be referred to without a qualifier
+	aliases := map[string]bool{}
+	aliasMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeAliased}}
+	for _, node := range astutil.NewNodeReader(c.ss.stmt).FindRecursive(aliasMatcher) {
+		if alias, ok := node.(*ast.Aliased).AliasedName.(*ast.Identifier); ok {
+			aliases[strings.ToUpper(alias.NoQuoteString())] = true
+		}
+	}
+
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+	for _, node := range astutil.NewNodeReader(c.ss.stmt).FindRecursive(matcher) {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || ident.IsWildcard() || c.ss.qualified[ident] != nil {
+			continue
+		}
+		col := c.ss.columnAt(ident)
+		if col == nil || aliases[strings.ToUpper(col.name)] {
+			continue
+		}
+		if c.ss.written[ident] && c.ss.verb != "UPDATE" {
+			continue
+		}
+		refs := c.columnTables(col.name, c.ss.innermostScope(ident))
+		if len(refs) < 2 {
+			continue
+		}
+
+		fixes := []*schemaFix{}
+		for _, ref := range refs {
+			qualifier := ref.Ident.String()
+			if ref.AliasIdent != nil {
+				qualifier = ref.AliasIdent.String()
+			}
+			fixes = append(fixes, &schemaFix{
+				title: fmt.Sprintf("Qualify as `%s.%s`", qualifier, ident.String()),
+				edits: []lsp.TextEdit{{
+					Range:   c.lines.nodeRange(ident),
+					NewText: qualifier + "." + ident.String(),
+				}},
+			})
+		}
+		message := fmt.Sprintf("column %q is ambiguous", col.name)
+		problems = append(problems, c.newProblem(ident, codeAmbiguousColumn, message, fixes))
+	}
+	return problems
+}
+
+// columnTables returns the tables of the nearest scope having a column
+// named name. It returns nil if a table of that scope can not be checked.
+func (c *schemaChecker) columnTables(name string, sc *scope) []*parseutil.TableRef {
+	for ; sc != nil; sc = sc.parent {
+		found := []*parseutil.TableRef{}
+		for _, ref := range sc.refs {
+			// The target of INSERT is not visible to its SELECT
+			if ref.Ident != nil && ref.Ident == c.ss.target && (c.ss.verb == "INSERT" || c.ss.verb == "REPLACE") {
+				continue
+			}
+			table, ok := c.resolved[ref]
+			if !ok {
+				return nil
+			}
+			cols, ok := c.dbCache.ColumnDatabase(table.db, table.tbl)
+			if !ok {
+				return nil
+			}
+			if hasColumn(cols, name) {
+				found = append(found, ref)
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// didYouMean suggests the candidates closest to the name of ident, the
+// closest first.
+func (c *schemaChecker) didYouMean(ident *ast.Identifier, candidates []string) []*schemaFix {
+	name := ident.NoQuoteString()
+	maxDistance := (len([]rune(name)) + 2) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	suggestions := []suggestion{}
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d <= maxDistance {
+			suggestions = append(suggestions, suggestion{name: candidate, distance: d})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+
+	fixes := []*schemaFix{}
+	for _, s := range suggestions {
+		fixes = append(fixes, &schemaFix{
+			title: fmt.Sprintf("Did you mean `%s`?", s.name),
+			edits: []lsp.TextEdit{{
+				Range:   c.lines.nodeRange(ident),
+				NewText: s.name,
+			}},
+		})
+	}
+	return fixes
+}
+
+// editDistance is the case insensitive Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func (c *schemaChecker) newProblem(ident *ast.Identifier, code, message string, fixes []*schemaFix) *schemaProblem {
+	diag := newDiagnostic(ident.Pos(), ident.End(), lsp.DSWarning, message)
+	diag.Range = c.lines.nodeRange(ident)
+	diag.Code = &code
+	return &schemaProblem{diag: diag, fixes: fixes}
+}
+
+func hasColumn(cols []*database.ColumnDesc, name string) bool {
+	for _, col := range cols {
+		if strings.EqualFold(col.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func newDiagnostic(from, to token.Pos, severity lsp.DiagnosticSeverity, message string) lsp.Diagnostic {
+	if token.ComparePos(from, to) == 0 {
+		to.Col++
+	}
+	return lsp.Diagnostic{
+		Range: lsp.Range{
+			Start: lsp.Position{
+				Line:      from.Line,
+				Character: from.Col,
+			},
+			End: lsp.Position{
+				Line:      to.Line,
+				Character: to.Col,
+			},
+		},
+		Severity: severity,
+		Source:   &diagnosticSource,
+		Message:  message,
+	}
+}
@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestDiagnostics(t *testing.T) {
+	type diag struct {
+		Range   lsp.Range
+		Message string
+	}
+	rng := func(sl, sc, el, ec int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: sl, Character: sc},
+			End:   lsp.Position{Line: el, Character: ec},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []diag
+	}{
+		{
+			name:  "valid",
+			input: "SELECT ID, Name FROM city WHERE (ID = 1);\n-- comment\ninsert into city (ID) values (1)",
+			want:  []diag{},
+		},
+		{
+			name:  "subquery at head",
+			input: "(SELECT 1) UNION (SELECT 2)",
+			want:  []diag{},
+		},
+		{
+			name:  "unclosed parenthesis",
+			input: "SELECT * FROM city WHERE (ID = 1",
+			want: []diag{
+				{Range: rng(0, 25, 0, 26), Message: "unclosed parenthesis"},
+			},
+		},
+		{
+			name:  "unclosed parenthesis before semicolon",
+			input: "SELECT count(ID FROM city; SELECT 1",
+			want: []diag{
+				{Range: rng(0, 12, 0, 13), Message: "unclosed parenthesis"},
+			},
+		},
+		{
+			name:  "unmatched closing parenthesis",
+			input: "SELECT * FROM city WHERE ID = 1)",
+			want: []diag{
+				{Range: rng(0, 31, 0, 32), Message: "unmatched closing parenthesis"},
+			},
+		},
+		{
+			name:  "unterminated string",
+			input: "SELECT * FROM city WHERE Name = 'Kab",
+			want: []diag{
+				{Range: rng(0, 32, 0, 36), Message: "unterminated string literal"},
+			},
+		},
+		{
+			name:  "unterminated string ending in an escaped quote",
+			input: "SELECT * FROM city WHERE Name = 'Kab''",
+			want: []diag{
+				{Range: rng(0, 32, 0, 38), Message: "unterminated string literal"},
+			},
+		},
+		{
+			name:  "string ending in an escaped quote",
+			input: "SELECT * FROM city WHERE Name = 'Kab'''",
+			want:  []diag{},
+		},
+		{
+			name:  "unclosed parenthesis after a tab",
+			input: "SELECT *\tFROM city WHERE (ID = 1",
+			want: []diag{
+				{Range: rng(0, 25, 0, 26), Message: "unclosed parenthesis"},
+			},
+		},
+		{
+			name:  "unterminated comment",
+			input: "SELECT 1;\n/* comment\nSELECT 2;",
+			want: []diag{
+				{Range: rng(1, 0, 2, 9), Message: "unterminated comment"},
+			},
+		},
+		{
+			name:  "unknown statement",
+			input: "SELECT 1;\n SELEC * FROM city;",
+			want: []diag{
+				{Range: rng(1, 1, 1, 6), Message: `unknown statement "SELEC"`},
+			},
+		},
+		{
+			name:  "routine body",
+			input: "CREATE PROCEDURE p() BEGIN IF x THEN SELECT CASE WHEN y THEN 1 END; END IF; RETURN 1; END;\nSELEC 1;",
+			want: []diag{
+				{Range: rng(1, 0, 1, 5), Message: `unknown statement "SELEC"`},
+			},
+		},
+		{
+			name:  "transaction",
+			input: "BEGIN;\nSELEC 1;\nCOMMIT;",
+			want: []diag{
+				{Range: rng(1, 0, 1, 5), Message: `unknown statement "SELEC"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []diag{}
+			for _, d := range diagnostics(tt.input, nil) {
+				if d.Severity != lsp.DSError {
+					t.Errorf("unexpected severity %d", d.Severity)
+				}
+				got = append(got, diag{Range: d.Range, Message: d.Message})
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch diagnostics (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSchemaDiagnostics(t *testing.T) {
+	type diag struct {
+		Range   lsp.Range
+		Message string
+	}
+	rng := func(sl, sc, el, ec int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: sl, Character: sc},
+			End:   lsp.Position{Line: el, Character: ec},
+		}
+	}
+
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []diag
+	}{
+		{
+			name:  "known tables and columns",
+			input: "SELECT c.ID, co.Name FROM city AS c JOIN world.country co ON c.CountryCode = co.Code WHERE city.ID = 1",
+			want:  []diag{},
+		},
+		{
+			name:  "unknown table",
+			input: "SELECT * FROM city JOIN cty ON city.ID = cty.ID",
+			want: []diag{
+				{Range: rng(0, 24, 0, 27), Message: `table "cty" does not exist`},
+			},
+		},
+		{
+			name:  "unknown table in sub query",
+			input: "SELECT * FROM city WHERE ID IN (SELECT ID FROM contry)",
+			want: []diag{
+				{Range: rng(0, 47, 0, 53), Message: `table "contry" does not exist`},
+			},
+		},
+		{
+			name:  "unknown column",
+			input: "SELECT ci.Nme FROM city ci",
+			want: []diag{
+				{Range: rng(0, 10, 0, 13), Message: `column "Nme" does not exist in table "city"`},
+			},
+		},
+		{
+			name:  "unknown column after a tab",
+			input: "SELECT\tci.Nme FROM city ci",
+			want: []diag{
+				{Range: rng(0, 10, 0, 13), Message: `column "Nme" does not exist in table "city"`},
+			},
+		},
+		{
+			name:  "unknown column in update",
+			input: "UPDATE city SET Name = 'x' WHERE city.CountyCode = 'JPN'",
+			want: []diag{
+				{Range: rng(0, 38, 0, 48), Message: `column "CountyCode" does not exist in table "city"`},
+			},
+		},
+		{
+			name:  "uncached schema",
+			input: "SELECT * FROM mysql.user",
+			want:  []diag{},
+		},
+		{
+			name:  "sub query and cte",
+			input: "WITH x AS (SELECT ID FROM city) SELECT x.Foo, s.Bar FROM x, (SELECT 1 AS Bar) AS s",
+			want:  []diag{},
+		},
+		{
+			name:  "created in document",
+			input: "CREATE TABLE IF NOT EXISTS foo (id int);\nINSERT INTO foo (id) VALUES (1)",
+			want:  []diag{},
+		},
+		{
+			name:  "function arguments",
+			input: "SELECT EXTRACT(YEAR FROM d) FROM city",
+			want:  []diag{},
+		},
+		{
+			name:  "ambiguous column",
+			input: "SELECT Name FROM city JOIN country ON city.CountryCode = country.Code WHERE ID = 1",
+			want: []diag{
+				{Range: rng(0, 7, 0, 11), Message: `column "Name" is ambiguous`},
+			},
+		},
+		{
+			name:  "columns joined with using",
+			input: "SELECT CountryCode FROM city JOIN countrylanguage USING (CountryCode)",
+			want:  []diag{},
+		},
+		{
+			name:  "insert from select",
+			input: "INSERT INTO city (Name) SELECT Name FROM country",
+			want:  []diag{},
+		},
+		{
+			name:  "undefined qualifier",
+			input: "SELECT c.ID, world.city.Name FROM city WHERE NEW.ID = 1",
+			want: []diag{
+				{Range: rng(0, 7, 0, 8), Message: `table or alias "c" is not defined`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []diag{}
+			for _, d := range schemaDiagnostics(tt.input, dbCache) {
+				if d.Severity != lsp.DSWarning {
+					t.Errorf("unexpected severity %d", d.Severity)
+				}
+				got = append(got, diag{Range: d.Range, Message: d.Message})
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch diagnostics (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// diagnosticsRecorder is a client sending the published diagnostics to a
+// channel.
+type diagnosticsRecorder chan lsp.PublishDiagnosticsParams
+
+func (r diagnosticsRecorder) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Method != "textDocument/publishDiagnostics" {
+		return
+	}
+	var params lsp.PublishDiagnosticsParams
+	if err := json.Unmarshal(*req.Params, &params); err == nil {
+		r <- params
+	}
+}
+
+func TestPublishDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Stop()
+	recorder := make(diagnosticsRecorder, 10)
+
+	client, serverPipe := net.Pipe()
+	connServer := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(serverPipe, jsonrpc2.VSCodeObjectCodec{}), server.Handler())
+	defer connServer.Close()
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), recorder)
+	defer conn.Close()
+
+	if err := conn.Call(ctx, "initialize", lsp.InitializeParams{}, nil); err != nil {
+		t.Fatal("conn.Call initialize:", err)
+	}
+	next := func() lsp.PublishDiagnosticsParams {
+		t.Helper()
+		select {
+		case params := <-recorder:
+			return params
+		case <-time.After(5 * time.Second):
+			t.Fatal("diagnostics were not published")
+		}
+		return lsp.PublishDiagnosticsParams{}
+	}
+
+	openParams := lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{
+			URI:     testFileURI,
+			Text:    "SELECT (",
+			Version: 1,
+		},
+	}
+	if err := conn.Notify(ctx, "textDocument/didOpen", openParams); err != nil {
+		t.Fatal("conn.Notify textDocument/didOpen:", err)
+	}
+	if got := next(); got.Version != 1 || len(got.Diagnostics) != 1 {
+		t.Errorf("unexpected diagnostics of the opened document %+v", got)
+	}
+
+	// Only the last of the changes in a row is checked
+	for i, text := range []string{"SELECT )", "SELECT 1"} {
+		changeParams := lsp.DidChangeTextDocumentParams{
+			TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: testFileURI, Version: i + 2},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: text}},
+		}
+		if err := conn.Notify(ctx, "textDocument/didChange", changeParams); err != nil {
+			t.Fatal("conn.Notify textDocument/didChange:", err)
+		}
+	}
+	if got := next(); got.Version != 3 || len(got.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics of the changed document %+v", got)
+	}
+	select {
+	case got := <-recorder:
+		t.Errorf("unexpected diagnostics %+v", got)
+	case <-time.After(2 * diagnosticsDelay):
+	}
+}
+
+func TestRepublishDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Stop()
+	recorder := make(diagnosticsRecorder, 10)
+
+	client, serverPipe := net.Pipe()
+	connServer := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(serverPipe, jsonrpc2.VSCodeObjectCodec{}), server.Handler())
+	defer connServer.Close()
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), recorder)
+	defer conn.Close()
+
+	if err := conn.Call(ctx, "initialize", lsp.InitializeParams{}, nil); err != nil {
+		t.Fatal("conn.Call initialize:", err)
+	}
+	next := func() lsp.PublishDiagnosticsParams {
+		t.Helper()
+		select {
+		case params := <-recorder:
+			return params
+		case <-time.After(5 * time.Second):
+			t.Fatal("diagnostics were not published")
+		}
+		return lsp.PublishDiagnosticsParams{}
+	}
+
+	openParams := lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{
+			URI:     testFileURI,
+			Text:    "SELECT ci.Nme FROM city ci",
+			Version: 1,
+		},
+	}
+	if err := conn.Notify(ctx, "textDocument/didOpen", openParams); err != nil {
+		t.Fatal("conn.Notify textDocument/didOpen:", err)
+	}
+	if got := next(); len(got.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics without the database %+v", got)
+	}
+
+	// The schema is checked once the database is loaded
+	didChangeConfigurationParams := lsp.DidChangeConfigurationParams{
+		Settings: struct {
+			SQLS *config.Config "json:\"sqls\""
+		}{
+			SQLS: &config.Config{
+				Connections: []*database.DBConfig{{Driver: "mock"}},
+			},
+		},
+	}
+	if err := conn.Call(ctx, "workspace/didChangeConfiguration", didChangeConfigurationParams, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeConfiguration:", err)
+	}
+	if got := next(); got.Version != 1 || len(got.Diagnostics) != 1 {
+		t.Errorf("unexpected diagnostics with the database %+v", got)
+	}
+
+	// And again once the database is switched
+	switchParams := lsp.ExecuteCommandParams{
+		Command:   CommandSwitchDatabase,
+		Arguments: []interface{}{"world2"},
+	}
+	if err := conn.Call(ctx, "workspace/executeCommand", switchParams, nil); err != nil {
+		t.Fatal("conn.Call workspace/executeCommand:", err)
+	}
+	if got := next(); got.Version != 1 || len(got.Diagnostics) != 1 {
+		t.Errorf("unexpected diagnostics of the switched database %+v", got)
+	}
+}
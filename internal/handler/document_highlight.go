@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+)
+
+func (s *Server) handleTextDocumentDocumentHighlight(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentHighlightParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return documentHighlights(f.Text, params)
+}
+
+// documentHighlights returns the occurrences in the current statement of the
+// alias, CTE, table, column alias or column under the cursor.
+func documentHighlights(text string, params lsp.DocumentHighlightParams) ([]lsp.DocumentHighlight, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(text)
+	pos := lines.tokenPos(params.Position)
+
+	nodeWalker := parseutil.NewNodeWalker(parsed, pos)
+	stmt, ok := nodeWalker.CurNodeTopMatched(astutil.NodeMatcher{
+		NodeTypes: []ast.NodeType{ast.TypeStatement},
+	}).(*ast.Statement)
+	if !ok {
+		return nil, nil
+	}
+	// The walker stops at a node ending at pos, such as the whitespace before
+	// an identifier, so the identifier is looked up in the statement instead
+	ident := identifierAt(stmt, pos)
+	if ident == nil {
+		return nil, nil
+	}
+
+	ss := newStatementScopes(stmt)
+	var occs []*occurrence
+	if sym := ss.symbolAt(ident); sym != nil {
+		occs = ss.occurrences(sym)
+	} else if def := ss.columnAliasAt(ident); def != nil {
+		occs = ss.columnAliasOccurrences(def)
+	} else if col := ss.columnAt(ident); col != nil {
+		occs = ss.columnOccurrences(col)
+	}
+
+	highlights := []lsp.DocumentHighlight{}
+	for _, occ := range occs {
+		kind := lsp.DHKRead
+		if occ.isWrite {
+			kind = lsp.DHKWrite
+		}
+		highlights = append(highlights, lsp.DocumentHighlight{
+			Range: lines.nodeRange(occ.ident),
+			Kind:  kind,
+		})
+	}
+	return highlights, nil
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestDocumentHighlight(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	tests := []struct {
+		name  string
+		input string
+		pos   lsp.Position
+		want  []string
+	}{
+		{
+			name:  "update set target",
+			input: "UPDATE city c SET Name = 'x', c.Population = Population + 1 WHERE ID = 1",
+			pos:   lsp.Position{Line: 0, Character: 45},
+			want: []string{
+				"write 0:32-0:42",
+				"read 0:45-0:55",
+			},
+		},
+		{
+			name:  "update alias",
+			input: "UPDATE city c SET Name = 'x', c.Population = Population + 1 WHERE ID = 1",
+			pos:   lsp.Position{Line: 0, Character: 30},
+			want: []string{
+				"write 0:12-0:13",
+				"read 0:30-0:31",
+			},
+		},
+		{
+			name:  "update table",
+			input: "UPDATE city c SET Name = 'x' WHERE ID = 1",
+			pos:   lsp.Position{Line: 0, Character: 7},
+			want: []string{
+				"write 0:7-0:11",
+			},
+		},
+		{
+			name:  "insert column list",
+			input: "INSERT INTO city (ID, Name) SELECT ID, Name FROM country",
+			pos:   lsp.Position{Line: 0, Character: 22},
+			want: []string{
+				"write 0:22-0:26",
+			},
+		},
+		{
+			name:  "insert select column",
+			input: "INSERT INTO city (ID) SELECT ID FROM country WHERE ID > 1",
+			pos:   lsp.Position{Line: 0, Character: 29},
+			want: []string{
+				"read 0:29-0:31",
+				"read 0:51-0:53",
+			},
+		},
+		{
+			name:  "unqualified column of a joined table",
+			input: "SELECT a.ID, b.ID FROM city a JOIN country b ON a.ID = b.ID WHERE ID = 1",
+			pos:   lsp.Position{Line: 0, Character: 66},
+			want: []string{
+				"read 0:9-0:11",
+				"read 0:15-0:17",
+				"read 0:50-0:52",
+				"read 0:57-0:59",
+				"read 0:66-0:68",
+			},
+		},
+		{
+			name:  "unqualified columns of sub queries",
+			input: "SELECT ID FROM city WHERE ID IN (SELECT ID FROM country)",
+			pos:   lsp.Position{Line: 0, Character: 7},
+			want: []string{
+				"read 0:7-0:9",
+				"read 0:26-0:28",
+			},
+		},
+		{
+			name:  "column alias",
+			input: "SELECT Name AS n FROM city ORDER BY n",
+			pos:   lsp.Position{Line: 0, Character: 36},
+			want: []string{
+				"write 0:15-0:16",
+				"read 0:36-0:37",
+			},
+		},
+		{
+			name:  "insert column list without space",
+			input: "INSERT INTO city(ID) VALUES (1)",
+			pos:   lsp.Position{Line: 0, Character: 17},
+			want: []string{
+				"write 0:17-0:19",
+			},
+		},
+		{
+			name:  "qualified column",
+			input: "SELECT a.ID, b.ID, Name FROM city a JOIN country b ON a.ID = b.ID",
+			pos:   lsp.Position{Line: 0, Character: 9},
+			want: []string{
+				"read 0:9-0:11",
+				"read 0:56-0:58",
+			},
+		},
+		{
+			name:  "current statement only",
+			input: "SELECT ID FROM city; SELECT ID FROM country",
+			pos:   lsp.Position{Line: 0, Character: 7},
+			want: []string{
+				"read 0:7-0:9",
+			},
+		},
+		{
+			name:  "alias after a tab",
+			input: "SELECT\n\tc.ID FROM city c",
+			pos:   lsp.Position{Line: 1, Character: 1},
+			want: []string{
+				"read 1:1-1:2",
+				"write 1:16-1:17",
+			},
+		},
+		{
+			name:  "alias after a surrogate pair",
+			input: "SELECT '😀', c.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 28},
+			want: []string{
+				"read 0:13-0:14",
+				"write 0:28-0:29",
+			},
+		},
+		{
+			name:  "function name",
+			input: "SELECT COUNT(ID) FROM city",
+			pos:   lsp.Position{Line: 0, Character: 7},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.DocumentHighlightParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{
+						URI: testFileURI,
+					},
+					Position: tt.pos,
+				},
+			}
+			var got []lsp.DocumentHighlight
+			if err := tx.conn.Call(tx.ctx, "textDocument/documentHighlight", params, &got); err != nil {
+				t.Fatal("conn.Call textDocument/documentHighlight:", err)
+			}
+			gotStrs := []string{}
+			for _, h := range got {
+				kind := "read"
+				if h.Kind == lsp.DHKWrite {
+					kind = "write"
+				}
+				r := h.Range
+				gotStrs = append(gotStrs, fmt.Sprintf("%s %d:%d-%d:%d", kind, r.Start.Line, r.Start.Character, r.End.Line, r.End.Character))
+			}
+			if diff := cmp.Diff(tt.want, gotStrs); diff != "" {
+				t.Errorf("unmatch highlights (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
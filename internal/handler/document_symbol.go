@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentDocumentSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return documentSymbols(f.Text)
+}
+
+func documentSymbols(text string) ([]lsp.DocumentSymbol, error) {
+	stmts, err := getStatements(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(text)
+	symbols := []lsp.DocumentSymbol{}
+	for _, stmt := range stmts {
+		if symbol, ok := statementSymbol(lines, stmt); ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols, nil
+}
+
+func statementSymbol(lines textLines, stmt *ast.Statement) (lsp.DocumentSymbol, bool) {
+	nodes := statementNodes(stmt)
+	if len(nodes) == 0 {
+		return lsp.DocumentSymbol{}, false
+	}
+
+	ctes := parseutil.ExtractCTEs(stmt)
+	verb := statementVerb(nodes, ctes)
+	kind := strings.ToUpper(strings.Fields(verb.String())[0])
+
+	var (
+		target    string
+		selection ast.Node = verb
+		children           = []lsp.DocumentSymbol{}
+	)
+	for _, cte := range ctes {
+		children = append(children, cteSymbol(lines, cte))
+	}
+	if info := parseutil.ExtractCreate(stmt); info != nil {
+		kind = "CREATE " + info.Kind
+		target = qualifiedName(info.DatabaseSchema, info.Name)
+		selection = info.Ident
+		children = append(children, lsp.DocumentSymbol{
+			Name:           target,
+			Detail:         info.Kind,
+			Kind:           createdSymbolKind(info.Kind),
+			Range:          lines.nodeRange(info.Ident),
+			SelectionRange: lines.nodeRange(info.Ident),
+		})
+	} else if ref := mainTableRef(stmt, ctes); ref != nil {
+		target = qualifiedName(ref.Info.DatabaseSchema, ref.Info.Name)
+		selection = ref.Ident
+	}
+
+	for _, subQuery := range parseutil.ExtractSubQueryAliases(stmt) {
+		if !isInCTE(subQuery, ctes) {
+			children = append(children, subQuerySymbol(lines, subQuery))
+		}
+	}
+
+	name := kind
+	if target != "" {
+		name = kind + " " + target
+	}
+	return lsp.DocumentSymbol{
+		Name:           name,
+		Detail:         kind,
+		Kind:           lsp.SKFunction,
+		Range:          lines.posRange(nodes[0].Pos(), stmt.End()),
+		SelectionRange: lines.nodeRange(selection),
+		Children:       children,
+	}, true
+}
+
+// statementVerb returns the node starting the statement. The verb of
+// WITH ... SELECT is the query following the CTEs.
+func statementVerb(nodes []ast.Node, ctes []*parseutil.CTEInfo) ast.Node {
+	if len(ctes) == 0 {
+		return nodes[0]
+	}
+	queryEnd := ctes[len(ctes)-1].Query.End()
+	for _, node := range nodes {
+		if token.ComparePos(node.Pos(), queryEnd) >= 0 {
+			return node
+		}
+	}
+	return nodes[0]
+}
+
+func cteSymbol(lines textLines, cte *parseutil.CTEInfo) lsp.DocumentSymbol {
+	children := []lsp.DocumentSymbol{}
+	for _, subQuery := range parseutil.ExtractSubQueryAliases(cte.Query) {
+		children = append(children, subQuerySymbol(lines, subQuery))
+	}
+	return lsp.DocumentSymbol{
+		Name:           cte.Name,
+		Detail:         "WITH",
+		Kind:           lsp.SKStruct,
+		Range:          lines.posRange(cte.Ident.Pos(), cte.Query.End()),
+		SelectionRange: lines.nodeRange(cte.Ident),
+		Children:       children,
+	}
+}
+
+func subQuerySymbol(lines textLines, aliased *ast.Aliased) lsp.DocumentSymbol {
+	children := []lsp.DocumentSymbol{}
+	if list, ok := aliased.RealName.(ast.TokenList); ok {
+		for _, subQuery := range parseutil.ExtractSubQueryAliases(list) {
+			children = append(children, subQuerySymbol(lines, subQuery))
+		}
+	}
+	return lsp.DocumentSymbol{
+		Name:           aliased.AliasedName.String(),
+		Detail:         "sub query",
+		Kind:           lsp.SKStruct,
+		Range:          lines.nodeRange(aliased),
+		SelectionRange: lines.nodeRange(aliased.AliasedName),
+		Children:       children,
+	}
+}
+
+func createdSymbolKind(kind string) lsp.SymbolKind {
+	switch kind {
+	case "TABLE", "VIEW":
+		return lsp.SKClass
+	case "INDEX":
+		return lsp.SKKey
+	case "FUNCTION", "PROCEDURE", "TRIGGER":
+		return lsp.SKFunction
+	case "SCHEMA", "DATABASE":
+		return lsp.SKNamespace
+	}
+	return lsp.SKObject
+}
+
+// mainTableRef returns the first table referenced at the top level of the
+// statement, outside of CTEs and sub queries.
+func mainTableRef(stmt *ast.Statement, ctes []*parseutil.CTEInfo) *parseutil.TableRef {
+	topLevel := map[ast.Node]bool{}
+	for _, node := range stmt.GetTokens() {
+		topLevel[node] = true
+		// INSERT INTO city(ID, Name) parses the table name as a function name
+		if fl, ok := node.(*ast.FunctionLiteral); ok {
+			topLevel[fl.GetTokens()[0]] = true
+		}
+		if list, ok := node.(*ast.IdentifierList); ok {
+			for _, ident := range list.GetIdentifiers() {
+				topLevel[ident] = true
+			}
+		}
+	}
+	for _, ref := range parseutil.ExtractTableRefs(stmt) {
+		if ref.Ident != nil && topLevel[ref.Node] && !isInCTE(ref.Node, ctes) {
+			return ref
+		}
+	}
+	return nil
+}
+
+func isInCTE(node ast.Node, ctes []*parseutil.CTEInfo) bool {
+	for _, cte := range ctes {
+		if token.ComparePos(cte.Query.Pos(), node.Pos()) <= 0 && token.ComparePos(node.End(), cte.Query.End()) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// statementNodes returns the nodes of the statement without whitespace,
+// comments and the terminating semicolon.
+func statementNodes(stmt *ast.Statement) []ast.Node {
+	nodes := []ast.Node{}
+	for _, node := range stmt.GetTokens() {
+		if tok, ok := node.(ast.Token); ok {
+			switch tok.GetToken().Kind {
+			case token.Whitespace, token.Comment, token.MultilineComment, token.Semicolon:
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func qualifiedName(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestDocumentSymbol(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "statements",
+			input: "SELECT ID FROM city;\nINSERT INTO world.country (Code) VALUES ('JPN');\nUPDATE city SET Name = 'x';\nDELETE FROM city",
+			want: []string{
+				"SELECT city [0:0-0:20] [0:15-0:19]",
+				"INSERT world.country [1:0-1:48] [1:18-1:25]",
+				"UPDATE city [2:0-2:27] [2:7-2:11]",
+				"DELETE city [3:0-3:16] [3:12-3:16]",
+			},
+		},
+		{
+			name:  "cte and sub query",
+			input: "WITH x AS (SELECT * FROM (SELECT ID FROM city) AS c)\nSELECT * FROM x JOIN (SELECT 1 AS ID) s ON x.ID = s.ID",
+			want: []string{
+				"SELECT x [0:0-1:54] [1:14-1:15]",
+				"  x [0:5-0:52] [0:5-0:6]",
+				"    c [0:25-0:51] [0:50-0:51]",
+				"  s [1:21-1:39] [1:38-1:39]",
+			},
+		},
+		{
+			name:  "create",
+			input: "CREATE TABLE IF NOT EXISTS foo (id int);\nCREATE VIEW v AS SELECT * FROM foo",
+			want: []string{
+				"CREATE TABLE foo [0:0-0:40] [0:27-0:30]",
+				"  foo [0:27-0:30] [0:27-0:30]",
+				"CREATE VIEW v [1:0-1:34] [1:12-1:13]",
+				"  v [1:12-1:13] [1:12-1:13]",
+			},
+		},
+		{
+			name:  "tab indent",
+			input: "\tCREATE TABLE\tfoo (id int)",
+			want: []string{
+				"CREATE TABLE foo [0:1-0:26] [0:14-0:17]",
+				"  foo [0:14-0:17] [0:14-0:17]",
+			},
+		},
+		{
+			name:  "no table",
+			input: "SELECT 1",
+			want: []string{
+				"SELECT [0:0-0:8] [0:0-0:6]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.DocumentSymbolParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+			}
+			var got []lsp.DocumentSymbol
+			if err := tx.conn.Call(tx.ctx, "textDocument/documentSymbol", params, &got); err != nil {
+				t.Fatal("conn.Call textDocument/documentSymbol:", err)
+			}
+			if diff := cmp.Diff(tt.want, flattenSymbols(got, 0)); diff != "" {
+				t.Errorf("unmatch document symbols (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func flattenSymbols(symbols []lsp.DocumentSymbol, depth int) []string {
+	res := []string{}
+	for _, s := range symbols {
+		res = append(res, fmt.Sprintf("%s%s %s %s", strings.Repeat("  ", depth), s.Name, formatRange(s.Range), formatRange(s.SelectionRange)))
+		res = append(res, flattenSymbols(s.Children, depth+1)...)
+	}
+	return res
+}
+
+func formatRange(r lsp.Range) string {
+	return fmt.Sprintf("[%d:%d-%d:%d]", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character)
+}
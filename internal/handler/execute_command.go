@@ -8,12 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/dialect"
 	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
 	"github.com/sqls-server/sqls/parser"
@@ -27,57 +31,14 @@ const (
 	CommandSwitchDatabase   = "switchDatabase"
 	CommandSwitchConnection = "switchConnections"
 	CommandShowTables       = "showTables"
+	CommandFetchNextPage    = "fetchNextPage"
+	CommandCancelQuery      = "cancelQuery"
+	CommandExplainQuery     = "explainQuery"
 )
 
-func (s *Server) handleTextDocumentCodeAction(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
-	if req.Params == nil {
-		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
-	}
-
-	var params lsp.CodeActionParams
-	if err := json.Unmarshal(*req.Params, &params); err != nil {
-		return nil, err
-	}
-
-	commands := []lsp.Command{
-		{
-			Title:     "Execute Query",
-			Command:   CommandExecuteQuery,
-			Arguments: []interface{}{params.TextDocument.URI},
-		},
-		{
-			Title:     "Show Databases",
-			Command:   CommandShowDatabases,
-			Arguments: []interface{}{},
-		},
-		{
-			Title:     "Show Schemas",
-			Command:   CommandShowSchemas,
-			Arguments: []interface{}{},
-		},
-		{
-			Title:     "Show Connections",
-			Command:   CommandShowConnections,
-			Arguments: []interface{}{},
-		},
-		{
-			Title:     "Switch Database",
-			Command:   CommandSwitchDatabase,
-			Arguments: []interface{}{},
-		},
-		{
-			Title:     "Switch Connections",
-			Command:   CommandSwitchConnection,
-			Arguments: []interface{}{},
-		},
-		{
-			Title:     "Show Tables",
-			Command:   CommandShowTables,
-			Arguments: []interface{}{},
-		},
-	}
-	return commands, nil
-}
+// outputJSON is the output format of executeQuery returning a QueryResult
+// for each statement instead of tables.
+const outputJSON = "json"
 
 func (s *Server) handleWorkspaceExecuteCommand(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
 	if req.Params == nil {
@@ -89,6 +50,14 @@ func (s *Server) handleWorkspaceExecuteCommand(ctx context.Context, conn *jsonrp
 		return nil, err
 	}
 
+	// Cancelling does not wait for the running command
+	if params.Command == CommandCancelQuery {
+		return s.cancelQuery(ctx, params)
+	}
+
+	// Commands run outside of the dispatch loop, one at a time
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
 	switch params.Command {
 	case CommandExecuteQuery:
 		return s.executeQuery(ctx, params)
@@ -99,11 +68,17 @@ func (s *Server) handleWorkspaceExecuteCommand(ctx context.Context, conn *jsonrp
 	case CommandShowConnections:
 		return s.showConnections(ctx, params)
 	case CommandSwitchDatabase:
+		defer s.republishDiagnostics(conn)
 		return s.switchDatabase(ctx, params)
 	case CommandSwitchConnection:
+		defer s.republishDiagnostics(conn)
 		return s.switchConnections(ctx, params)
 	case CommandShowTables:
 		return s.showTables(ctx, params)
+	case CommandFetchNextPage:
+		return s.fetchNextPage(ctx, params)
+	case CommandExplainQuery:
+		return s.explainQuery(ctx, params)
 	}
 	return nil, fmt.Errorf("unsupported command: %v", params.Command)
 }
@@ -113,37 +88,27 @@ func (s *Server) executeQuery(ctx context.Context, params lsp.ExecuteCommandPara
 	if s.dbConn == nil {
 		return nil, errors.New("database connection is not open")
 	}
-	if len(params.Arguments) == 0 {
-		return nil, fmt.Errorf("required arguments were not provided: <File URI>")
+	args, err := parseExecuteQueryArgs(params)
+	if err != nil {
+		return nil, err
 	}
-	uri, ok := params.Arguments[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("specify the file uri as a string")
+	if err := s.resolveOutputFile(args); err != nil {
+		return nil, err
 	}
-	f, ok := s.files[uri]
+	f, ok := s.file(args.uri)
 	if !ok {
-		return nil, fmt.Errorf("document not found, %q", uri)
-	}
-
-	showVertical := false
-	if len(params.Arguments) > 1 {
-		showVerticalFlag, ok := params.Arguments[1].(string)
-		if ok {
-			if showVerticalFlag == "-show-vertical" {
-				showVertical = true
-			}
-		}
+		return nil, fmt.Errorf("document not found, %q", args.uri)
 	}
 
 	// extract target query
 	text := f.Text
-	if params.Range != nil {
+	if args.rng != nil {
 		text = extractRangeText(
 			text,
-			params.Range.Start.Line,
-			params.Range.Start.Character,
-			params.Range.End.Line,
-			params.Range.End.Character,
+			args.rng.Start.Line,
+			args.rng.Start.Character,
+			args.rng.End.Line,
+			args.rng.End.Character,
 		)
 	}
 	stmts, err := getStatements(text)
@@ -151,31 +116,345 @@ func (s *Server) executeQuery(ctx context.Context, params lsp.ExecuteCommandPara
 		return nil, err
 	}
 
-	// execute statements
+	// execute statements, leaving the rows of the last truncated query for
+	// fetchNextPage
+	s.closeCursor()
+	maxRows := s.curDBCfg.MaxRows
+	if args.maxRows != nil {
+		maxRows = *args.maxRows
+	}
+	results, err := s.executeQueryResults(ctx, stmts, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	return formatResults(results, args, s.driver())
+}
+
+// formatResults returns the results of executeQuery in the output selected by
+// args, for a database of driver.
+func formatResults(results []*QueryResult, args *executeQueryArgs, driver dialect.DatabaseDriver) (interface{}, error) {
+	if args.output == outputJSON {
+		if args.outputFile == "" {
+			return results, nil
+		}
+		buf, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return writeOutputFile(args, buf, results)
+	}
+
+	output := args.output
+	if output == "" {
+		output = "table"
+		if args.showVertical {
+			output = "vertical"
+		}
+	}
+	formatter := resultFormatters[output](driver)
 	buf := new(bytes.Buffer)
+	for _, res := range results {
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		if err := formatter.format(buf, res); err != nil {
+			return nil, err
+		}
+	}
+	if args.outputFile != "" {
+		return writeOutputFile(args, buf.Bytes(), results)
+	}
+	return buf.String(), nil
+}
+
+// writeOutputFile writes the output of executeQuery to the output file of
+// args, and returns the message reporting it. An existing file is only
+// replaced with "-overwrite".
+func writeOutputFile(args *executeQueryArgs, output []byte, results []*QueryResult) (string, error) {
+	path := args.outputFile
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !args.overwrite {
+		flag |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flag, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("%s already exists, add -overwrite to replace it", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(output); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	var rows int
+	var truncated bool
+	for _, res := range results {
+		rows += len(res.Rows)
+		truncated = truncated || res.Truncated
+	}
+	if truncated {
+		return fmt.Sprintf("%d rows written to %s, truncated at the max rows", rows, path), nil
+	}
+	return fmt.Sprintf("%d rows written to %s", rows, path), nil
+}
+
+// resolveOutputFile resolves the output file of args against the workspace
+// root, refusing the paths outside of it, as the commands may come from any
+// client connected.
+func (s *Server) resolveOutputFile(args *executeQueryArgs) error {
+	if args.outputFile == "" {
+		return nil
+	}
+	s.cfgMu.RLock()
+	root := s.rootPath
+	s.cfgMu.RUnlock()
+	if root == "" {
+		return errors.New("the output file can not be written without a workspace root")
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	path := args.outputFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	// The directory is resolved, so that a link does not lead out of the root
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	path = filepath.Join(dir, filepath.Base(path))
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("the output file must be in the workspace root %s: %s", root, args.outputFile)
+	}
+	args.outputFile = path
+	return nil
+}
+
+// QueryResult is the result of a statement executed by executeQuery, returned
+// as is with "-output=json" and written by a resultFormatter otherwise.
+// Columns and Rows are set for queries, RowsAffected for the other statements.
+// Execution stops at the first statement that fails, with Error set.
+type QueryResult struct {
+	Statement    int                `json:"statement"`
+	Query        string             `json:"query"`
+	Columns      []*database.Column `json:"columns"`
+	Rows         [][]interface{}    `json:"rows"`
+	RowsAffected *int64             `json:"rowsAffected,omitempty"`
+	// Truncated is set when the query has more rows than the max rows.
+	Truncated bool `json:"truncated,omitempty"`
+	// Cursor is passed to fetchNextPage to fetch the rows left. Only the last
+	// truncated query of an execution has a cursor.
+	Cursor string `json:"cursor,omitempty"`
+	// ElapsedMS is the execution time in milliseconds
+	ElapsedMS float64 `json:"elapsedMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// executeQueryResults executes stmts, fetching at most maxRows rows of each
+// query, or all of them if maxRows is 0.
+func (s *Server) executeQueryResults(ctx context.Context, stmts []*ast.Statement, maxRows int) ([]*QueryResult, error) {
+	results := []*QueryResult{}
+	var cursor *queryCursor
 	for _, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			cursor.close()
+			return nil, err
+		}
 		query := strings.TrimSpace(stmt.String())
 		if query == "" {
 			continue
 		}
 
+		res := &QueryResult{
+			Statement: len(results),
+			Query:     query,
+		}
+		start := time.Now()
+		var err error
 		if _, isQuery := database.QueryExecType(query, ""); isQuery {
-			res, err := s.query(ctx, query, showVertical)
-			if err != nil {
-				return nil, err
+			var c *queryCursor
+			c, err = s.queryResult(ctx, res, maxRows)
+			if c != nil {
+				cursor.close()
+				cursor = c
 			}
-			fmt.Fprintln(buf, res)
 		} else {
-			res, err := s.exec(ctx, query, showVertical)
+			err = s.execResult(ctx, res)
+		}
+		res.ElapsedMS = float64(time.Since(start).Microseconds()) / 1000
+		results = append(results, res)
+		if err != nil {
+			// A cancelled request fails as a whole
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				cursor.close()
+				return nil, ctxErr
+			}
+			res.Error = err.Error()
+			break
+		}
+	}
+	if cursor != nil {
+		s.keepCursor(cursor)
+	}
+	return results, nil
+}
+
+// queryResult fetches the first maxRows rows of the query of res. If rows are
+// left, the query is returned as a cursor for the next pages instead of being
+// stopped.
+func (s *Server) queryResult(ctx context.Context, res *QueryResult, maxRows int) (*queryCursor, error) {
+	repo, err := s.newDBRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	st := s.newStatement(repo)
+	s.startStatement(ctx, st)
+	defer s.stopStatement(st)
+	rows, err := st.query(repo, res.Query)
+	if err != nil {
+		st.close()
+		return nil, st.err(err)
+	}
+	columns, err := database.ColumnTypes(rows)
+	if err != nil {
+		st.cancel(context.Canceled)
+		rows.Close()
+		st.close()
+		return nil, err
+	}
+	c := &queryCursor{
+		res:      res,
+		rows:     database.NewRowCursor(rows, len(columns)),
+		pageSize: maxRows,
+		stmt:     st,
+	}
+	typedRows, more, err := c.rows.Fetch(maxRows)
+	if err != nil {
+		err = st.err(err)
+		c.close()
+		return nil, err
+	}
+	res.Columns = columns
+	res.Rows = typedRows
+	res.Truncated = more
+	if !more {
+		c.close()
+		return nil, nil
+	}
+	return c, nil
+}
+
+func (s *Server) execResult(ctx context.Context, res *QueryResult) error {
+	repo, err := s.newDBRepository(ctx)
+	if err != nil {
+		return err
+	}
+	st := s.newStatement(repo)
+	defer st.close()
+	s.startStatement(ctx, st)
+	result, err := st.exec(repo, res.Query)
+	s.stopStatement(st)
+	if err != nil {
+		return st.err(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	res.RowsAffected = &rowsAffected
+	return nil
+}
+
+type executeQueryArgs struct {
+	uri          string
+	showVertical bool
+	output       string
+	outputFile   string
+	overwrite    bool
+	maxRows      *int
+	rng          *lsp.Range
+}
+
+// parseFlag parses a flag of executeQuery, ignoring the unknown ones.
+func (args *executeQueryArgs) parseFlag(v string) error {
+	if v == "-show-vertical" {
+		args.showVertical = true
+	}
+	if v == "-overwrite" {
+		args.overwrite = true
+	}
+	if output, ok := strings.CutPrefix(v, "-output="); ok {
+		if _, ok := resultFormatters[output]; !ok && output != outputJSON {
+			return fmt.Errorf("unsupported output format: %q", output)
+		}
+		args.output = output
+	}
+	if path, ok := strings.CutPrefix(v, "-output-file="); ok {
+		if path == "" {
+			return fmt.Errorf("specify the path of the output file")
+		}
+		args.outputFile = path
+	}
+	if n, ok := strings.CutPrefix(v, "-max-rows="); ok {
+		maxRows, err := strconv.Atoi(n)
+		if err != nil || maxRows < 0 {
+			return fmt.Errorf("specify the max rows as a number, %q", n)
+		}
+		args.maxRows = &maxRows
+	}
+	return nil
+}
+
+// parseExecuteQueryArgs parses the arguments of executeQuery: the file URI,
+// optionally followed by "-show-vertical", "-output=<format>",
+// "-output-file=<path>", "-overwrite", "-max-rows=<n>" and the range to
+// execute. Clients that can not send a range as an argument may set
+// params.Range instead.
+func parseExecuteQueryArgs(params lsp.ExecuteCommandParams) (*executeQueryArgs, error) {
+	if len(params.Arguments) == 0 {
+		return nil, fmt.Errorf("required arguments were not provided: <File URI>")
+	}
+	uri, ok := params.Arguments[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("specify the file uri as a string")
+	}
+
+	args := &executeQueryArgs{
+		uri: uri,
+		rng: params.Range,
+	}
+	for _, arg := range params.Arguments[1:] {
+		switch v := arg.(type) {
+		case string:
+			if err := args.parseFlag(v); err != nil {
+				return nil, err
+			}
+		case map[string]interface{}:
+			b, err := json.Marshal(v)
 			if err != nil {
 				return nil, err
 			}
-			fmt.Fprintln(buf, res)
+			var rng lsp.Range
+			if err := json.Unmarshal(b, &rng); err != nil {
+				return nil, fmt.Errorf("specify the range as an object: %w", err)
+			}
+			args.rng = &rng
 		}
 	}
-	return buf.String(), nil
+	return args, nil
 }
 
+// extractRangeText returns the text in the range, whose characters are UTF-16
+// columns as in LSP positions.
 func extractRangeText(text string, startLine, startChar, endLine, endChar int) string {
 	writer := bytes.NewBufferString("")
 	scanner := bufio.NewScanner(strings.NewReader(text))
@@ -187,10 +466,13 @@ func extractRangeText(text string, startLine, startChar, endLine, endChar int) s
 			st, en := 0, len(t)
 
 			if i == startLine {
-				st = startChar
+				st = utf16Offset(t, startChar)
 			}
 			if i == endLine {
-				en = endChar
+				en = utf16Offset(t, endChar)
+			}
+			if st > en {
+				st = en
 			}
 
 			writer.Write([]byte(t[st:en]))
@@ -203,67 +485,6 @@ func extractRangeText(text string, startLine, startChar, endLine, endChar int) s
 	return writer.String()
 }
 
-func (s *Server) query(ctx context.Context, query string, vertical bool) (string, error) {
-	repo, err := s.newDBRepository(ctx)
-	if err != nil {
-		return "", err
-	}
-	rows, err := repo.Query(ctx, query)
-	if err != nil {
-		return "", err
-	}
-	columns, err := database.Columns(rows)
-	if err != nil {
-		return "", err
-	}
-	stringRows, err := database.ScanRows(rows, len(columns))
-	if err != nil {
-		return "", err
-	}
-
-	buf := new(bytes.Buffer)
-	if vertical {
-		table := newVerticalTableWriter(buf)
-		table.setHeaders(columns)
-		for _, stringRow := range stringRows {
-			table.appendRow(stringRow)
-		}
-		table.render()
-	} else {
-		table := tablewriter.NewWriter(buf)
-		table.SetHeader(columns)
-		for _, stringRow := range stringRows {
-			table.Append(stringRow)
-		}
-		table.Render()
-	}
-	fmt.Fprintf(buf, "%d rows in set", len(stringRows))
-	fmt.Fprintln(buf, "")
-	fmt.Fprintln(buf, "")
-	return buf.String(), nil
-}
-
-func (s *Server) exec(ctx context.Context, query string, vertical bool) (string, error) {
-	repo, err := s.newDBRepository(ctx)
-	if err != nil {
-		return "", err
-	}
-	result, err := repo.Exec(ctx, query)
-	if err != nil {
-		return "", err
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return "", err
-	}
-
-	buf := new(bytes.Buffer)
-	fmt.Fprintf(buf, "Query OK, %d row affected", rowsAffected)
-	fmt.Fprintln(buf, "")
-	fmt.Fprintln(buf, "")
-	return buf.String(), nil
-}
-
 func (s *Server) showDatabases(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
 	repo, err := s.newDBRepository(ctx)
 	if err != nil {
@@ -301,7 +522,7 @@ func (s *Server) switchDatabase(ctx context.Context, params lsp.ExecuteCommandPa
 	s.curDBName = dbName
 
 	// close and reconnection to database
-	if err := s.reconnectionDB(ctx); err != nil {
+	if err := s.reconnectionDB(ctx, true); err != nil {
 		return nil, err
 	}
 
@@ -366,7 +587,7 @@ func (s *Server) switchConnections(ctx context.Context, params lsp.ExecuteComman
 	s.curConnectionIndex = index
 
 	// close and reconnection to database
-	if err := s.reconnectionDB(ctx); err != nil {
+	if err := s.reconnectionDB(ctx, true); err != nil {
 		return nil, err
 	}
 
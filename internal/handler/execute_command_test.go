@@ -3,6 +3,8 @@ package handler
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sqls-server/sqls/internal/config"
 	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
@@ -55,6 +57,173 @@ func Test_executeQuery(t *testing.T) {
 	// pass error
 }
 
+func Test_executeQueryJSON(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         "sqlite3",
+				DataSourceName: "file:execute_query_json?mode=memory&cache=shared",
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	text := "CREATE TABLE city (id INTEGER, name TEXT, population INTEGER);\n" +
+		"INSERT INTO city VALUES (1, 'Kabul', 1780000), (2, 'Qandahar', NULL);\n" +
+		"SELECT id, name, population FROM city ORDER BY id;\n" +
+		"SELECT * FROM country;\n" +
+		"SELECT 1;"
+	tx.textDocumentDidOpen(t, testFileURI, text)
+
+	executeCommandParams := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI, "-output=json"},
+	}
+	var got []*QueryResult
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", executeCommandParams, &got); err != nil {
+		t.Fatal("conn.Call workspace/executeCommand:", err)
+	}
+
+	rowsAffected := func(n int64) *int64 { return &n }
+	want := []*QueryResult{
+		{
+			Statement:    0,
+			Query:        "CREATE TABLE city (id INTEGER, name TEXT, population INTEGER);",
+			RowsAffected: rowsAffected(0),
+		},
+		{
+			Statement:    1,
+			Query:        "INSERT INTO city VALUES (1, 'Kabul', 1780000), (2, 'Qandahar', NULL);",
+			RowsAffected: rowsAffected(2),
+		},
+		{
+			Statement: 2,
+			Query:     "SELECT id, name, population FROM city ORDER BY id;",
+			Columns: []*database.Column{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "name", Type: "TEXT"},
+				{Name: "population", Type: "INTEGER"},
+			},
+			Rows: [][]interface{}{
+				{1.0, "Kabul", 1780000.0},
+				{2.0, "Qandahar", nil},
+			},
+		},
+		{
+			Statement: 3,
+			Query:     "SELECT * FROM country;",
+			Error:     "no such table: country",
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(QueryResult{}, "ElapsedMS")); diff != "" {
+		t.Errorf("unmatch results (- want, + got):\n%s", diff)
+	}
+}
+
+func Test_parseExecuteQueryArgs(t *testing.T) {
+	rng := lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 2},
+		End:   lsp.Position{Line: 3, Character: 4},
+	}
+	tests := []struct {
+		name    string
+		params  lsp.ExecuteCommandParams
+		want    *executeQueryArgs
+		wantErr bool
+	}{
+		{
+			name: "uri only",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql"},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql"},
+		},
+		{
+			name: "vertical with params range",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-show-vertical"},
+				Range:     &rng,
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", showVertical: true, rng: &rng},
+		},
+		{
+			name: "range argument",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{
+					"file:///test.sql",
+					map[string]interface{}{
+						"start": map[string]interface{}{"line": 1.0, "character": 2.0},
+						"end":   map[string]interface{}{"line": 3.0, "character": 4.0},
+					},
+				},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", rng: &rng},
+		},
+		{
+			name: "json output",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-output=json"},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", output: outputJSON},
+		},
+		{
+			name: "csv output to a file",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-output=csv", "-output-file=/tmp/result.csv"},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", output: "csv", outputFile: "/tmp/result.csv"},
+		},
+		{
+			name: "overwrite the output file",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-output-file=result.txt", "-overwrite"},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", outputFile: "result.txt", overwrite: true},
+		},
+		{
+			name: "max rows",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-max-rows=100"},
+			},
+			want: &executeQueryArgs{uri: "file:///test.sql", maxRows: func() *int { n := 100; return &n }()},
+		},
+		{
+			name: "invalid max rows",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-max-rows=-1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported output",
+			params: lsp.ExecuteCommandParams{
+				Arguments: []interface{}{"file:///test.sql", "-output=xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no arguments",
+			params:  lsp.ExecuteCommandParams{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExecuteQueryArgs(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(executeQueryArgs{})); diff != "" {
+				t.Errorf("unmatch args (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func Test_extractRangeText(t *testing.T) {
 	type args struct {
 		text      string
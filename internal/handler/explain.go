@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) explainQuery(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
+	if s.dbConn == nil {
+		return nil, errors.New("database connection is not open")
+	}
+	args, err := parseExplainQueryArgs(params)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := s.file(args.uri)
+	if !ok {
+		return nil, fmt.Errorf("document not found, %q", args.uri)
+	}
+	query, err := explainTarget(f.Text, args)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := s.newDBRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st := s.newStatement(repo)
+	defer st.close()
+	s.startStatement(ctx, st)
+	nodes, err := st.explain(repo, query)
+	s.stopStatement(st)
+	if errors.Is(err, database.ErrNotImplementation) {
+		return nil, fmt.Errorf("explain is not supported by the %s driver", s.curDBCfg.Driver)
+	}
+	if err != nil {
+		return nil, st.err(err)
+	}
+
+	if args.output == outputJSON {
+		return nodes, nil
+	}
+	return database.RenderPlan(nodes), nil
+}
+
+// explainTarget returns the statement of text under the position of args, or
+// the first statement in its range.
+func explainTarget(text string, args *explainQueryArgs) (string, error) {
+	if args.pos != nil {
+		stmts, err := getStatements(text)
+		if err != nil {
+			return "", err
+		}
+		stmt := statementAt(stmts, token.Pos{Line: args.pos.Line, Col: args.pos.Character})
+		if stmt == nil || len(statementNodes(stmt)) == 0 {
+			return "", fmt.Errorf("statement not found at %d:%d", args.pos.Line, args.pos.Character)
+		}
+		return strings.TrimSpace(stmt.String()), nil
+	}
+
+	text = extractRangeText(
+		text,
+		args.rng.Start.Line,
+		args.rng.Start.Character,
+		args.rng.End.Line,
+		args.rng.End.Character,
+	)
+	stmts, err := getStatements(text)
+	if err != nil {
+		return "", err
+	}
+	for _, stmt := range stmts {
+		if len(statementNodes(stmt)) > 0 {
+			return strings.TrimSpace(stmt.String()), nil
+		}
+	}
+	return "", errors.New("statement not found in the range")
+}
+
+type explainQueryArgs struct {
+	uri    string
+	output string
+	pos    *lsp.Position
+	rng    *lsp.Range
+}
+
+// parseExplainQueryArgs parses the arguments of explainQuery: the file URI,
+// followed by the position of the statement or a range, optionally with
+// "-output=json" to return the plan as JSON. Clients that can not send a range
+// as an argument may set params.Range instead.
+func parseExplainQueryArgs(params lsp.ExecuteCommandParams) (*explainQueryArgs, error) {
+	if len(params.Arguments) == 0 {
+		return nil, fmt.Errorf("required arguments were not provided: <File URI> <Position>")
+	}
+	uri, ok := params.Arguments[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("specify the file uri as a string")
+	}
+
+	args := &explainQueryArgs{
+		uri: uri,
+		rng: params.Range,
+	}
+	for _, arg := range params.Arguments[1:] {
+		switch v := arg.(type) {
+		case string:
+			if output, ok := strings.CutPrefix(v, "-output="); ok {
+				if output != outputJSON {
+					return nil, fmt.Errorf("unsupported output format: %q", output)
+				}
+				args.output = output
+			}
+		case map[string]interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if _, isRange := v["start"]; isRange {
+				var rng lsp.Range
+				if err := json.Unmarshal(b, &rng); err != nil {
+					return nil, fmt.Errorf("specify the range as an object: %w", err)
+				}
+				args.rng = &rng
+				args.pos = nil
+			} else {
+				var pos lsp.Position
+				if err := json.Unmarshal(b, &pos); err != nil {
+					return nil, fmt.Errorf("specify the position as an object: %w", err)
+				}
+				args.pos = &pos
+				args.rng = nil
+			}
+		}
+	}
+	if args.pos == nil && args.rng == nil {
+		return nil, fmt.Errorf("required arguments were not provided: <Position>")
+	}
+	return args, nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestExplainQuery(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         "sqlite3",
+				DataSourceName: "file:explain_query?mode=memory&cache=shared",
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	input := "CREATE TABLE city (id INTEGER, name TEXT);\nSELECT name FROM city ORDER BY name;\nSELECT * FROM city WHERE id = 1;"
+	tx.textDocumentDidOpen(t, testFileURI, input)
+
+	create := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI, lsp.Range{End: lsp.Position{Line: 0, Character: 42}}},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", create, nil); err != nil {
+		t.Fatal("executeQuery:", err)
+	}
+
+	// The statement under the position is explained
+	params := lsp.ExecuteCommandParams{
+		Command:   CommandExplainQuery,
+		Arguments: []interface{}{testFileURI, lsp.Position{Line: 1, Character: 10}},
+	}
+	var got string
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got); err != nil {
+		t.Fatal("explainQuery:", err)
+	}
+	want := "SCAN city\nUSE TEMP B-TREE FOR ORDER BY\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch plan (- want, + got):\n%s", diff)
+	}
+
+	// So is the first statement of the range
+	params = lsp.ExecuteCommandParams{
+		Command: CommandExplainQuery,
+		Arguments: []interface{}{
+			testFileURI,
+			"-output=json",
+			lsp.Range{Start: lsp.Position{Line: 2, Character: 0}, End: lsp.Position{Line: 2, Character: 32}},
+		},
+	}
+	var gotNodes []*database.PlanNode
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &gotNodes); err != nil {
+		t.Fatal("explainQuery:", err)
+	}
+	wantNodes := []*database.PlanNode{{Operation: "SCAN city"}}
+	if diff := cmp.Diff(wantNodes, gotNodes); diff != "" {
+		t.Errorf("unmatch plan nodes (- want, + got):\n%s", diff)
+	}
+
+	// Blank lines have no statement
+	params = lsp.ExecuteCommandParams{
+		Command:   CommandExplainQuery,
+		Arguments: []interface{}{testFileURI, lsp.Position{Line: 5, Character: 0}},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got); err == nil {
+		t.Error("expected error for the position without statement")
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentFoldingRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.FoldingRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return foldingRanges(f.Text)
+}
+
+// foldingRanges returns the folds of multi-line statements, sub queries,
+// CASE expressions and runs of comments.
+func foldingRanges(text string) ([]lsp.FoldingRange, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	folds := []lsp.FoldingRange{}
+	addFold := func(startLine, endLine int, kind lsp.FoldingRangeKind) {
+		if startLine < endLine {
+			folds = append(folds, lsp.FoldingRange{
+				StartLine: startLine,
+				EndLine:   endLine,
+				Kind:      kind,
+			})
+		}
+	}
+
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		nodes := statementNodes(stmt)
+		if len(nodes) == 0 {
+			continue
+		}
+		addFold(nodes[0].Pos().Line, nodes[len(nodes)-1].End().Line, "")
+	}
+
+	blockMatcher := astutil.NodeMatcher{
+		NodeTypes: []ast.NodeType{ast.TypeParenthesis, ast.TypeSwitchCase},
+	}
+	for _, node := range astutil.NewNodeReader(parsed).FindRecursive(blockMatcher) {
+		if _, ok := node.(*ast.Parenthesis); ok && !parseutil.IsSubQuery(node) {
+			continue
+		}
+		addFold(node.Pos().Line, blockEndLine(node.(ast.TokenList)), "")
+	}
+
+	var run []ast.Node
+	flushRun := func() {
+		if len(run) > 0 {
+			addFold(run[0].Pos().Line, run[len(run)-1].End().Line, lsp.FRKComment)
+		}
+		run = nil
+	}
+	// prevLine is the line the last token other than whitespace ends on
+	prevLine := -1
+	for _, leaf := range leafNodes(parsed) {
+		tok, ok := leaf.(ast.Token)
+		if !ok {
+			flushRun()
+			prevLine = leaf.End().Line
+			continue
+		}
+		switch tok.GetToken().Kind {
+		case token.Comment, token.MultilineComment:
+			// A blank line separates runs of comments
+			if len(run) > 0 && leaf.Pos().Line > run[len(run)-1].End().Line+1 {
+				flushRun()
+			}
+			// A comment following code on its line does not start a run
+			if len(run) > 0 || leaf.Pos().Line > prevLine {
+				run = append(run, leaf)
+			}
+		case token.Whitespace:
+			continue
+		default:
+			flushRun()
+		}
+		prevLine = leaf.End().Line
+	}
+	flushRun()
+
+	sort.SliceStable(folds, func(i, j int) bool {
+		if folds[i].StartLine != folds[j].StartLine {
+			return folds[i].StartLine < folds[j].StartLine
+		}
+		return folds[i].EndLine > folds[j].EndLine
+	})
+	uniq := []lsp.FoldingRange{}
+	for i, fold := range folds {
+		if i > 0 && fold == folds[i-1] {
+			continue
+		}
+		uniq = append(uniq, fold)
+	}
+	return uniq, nil
+}
+
+// blockEndLine returns the last line to fold of a block. A closing token
+// such as ")" or END starting its own line stays visible.
+func blockEndLine(list ast.TokenList) int {
+	toks := list.GetTokens()
+	last := toks[len(toks)-1]
+	for i := len(toks) - 2; i >= 0; i-- {
+		tok, ok := toks[i].(ast.Token)
+		if !ok || tok.GetToken().Kind != token.Whitespace {
+			break
+		}
+		if strings.Contains(toks[i].String(), "\n") {
+			return last.Pos().Line - 1
+		}
+	}
+	return last.End().Line
+}
+
+// leafNodes returns the nodes of the tree that have no children, in source
+// order.
+func leafNodes(node ast.Node) []ast.Node {
+	list, ok := node.(ast.TokenList)
+	if !ok {
+		return []ast.Node{node}
+	}
+	leaves := []ast.Node{}
+	for _, child := range list.GetTokens() {
+		leaves = append(leaves, leafNodes(child)...)
+	}
+	return leaves
+}
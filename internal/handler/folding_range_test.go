@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestFoldingRange(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []lsp.FoldingRange
+	}{
+		{
+			name: "statements and blocks",
+			input: `-- header
+-- comment
+
+SELECT
+  ID,
+  CASE
+    WHEN Population > 1000 THEN 'big'
+    ELSE 'small'
+  END AS size
+FROM city
+WHERE CountryCode IN (
+  SELECT Code
+  FROM country
+);
+/*
+ block
+*/
+SELECT 1;
+SELECT 2
+FROM city;`,
+			want: []lsp.FoldingRange{
+				{StartLine: 0, EndLine: 1, Kind: lsp.FRKComment},
+				{StartLine: 3, EndLine: 13},
+				{StartLine: 5, EndLine: 7},
+				{StartLine: 10, EndLine: 12},
+				{StartLine: 14, EndLine: 16, Kind: lsp.FRKComment},
+				{StartLine: 18, EndLine: 19},
+			},
+		},
+		{
+			name: "closing on the same line",
+			input: `SELECT * FROM (SELECT ID
+  FROM city) AS t`,
+			want: []lsp.FoldingRange{
+				{StartLine: 0, EndLine: 1},
+			},
+		},
+		{
+			name: "separated comments",
+			input: `-- a
+
+-- b
+SELECT 1`,
+			want: []lsp.FoldingRange{},
+		},
+		{
+			name: "trailing comment",
+			input: `SELECT 1; -- x
+-- y
+-- z
+SELECT 2; /* a
+b */
+SELECT 3`,
+			want: []lsp.FoldingRange{
+				{StartLine: 1, EndLine: 2, Kind: lsp.FRKComment},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.FoldingRangeParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+			}
+			var got []lsp.FoldingRange
+			if err := tx.conn.Call(tx.ctx, "textDocument/foldingRange", params, &got); err != nil {
+				t.Fatal("conn.Call textDocument/foldingRange:", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch folding ranges (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
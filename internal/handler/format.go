@@ -20,7 +20,7 @@ func (s *Server) handleTextDocumentFormatting(ctx context.Context, conn *jsonrpc
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.file(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
@@ -45,12 +45,40 @@ func (s *Server) handleTextDocumentRangeFormatting(ctx context.Context, conn *js
 		return nil, err
 	}
 
-	_, ok := s.files[params.TextDocument.URI]
+	f, ok := s.file(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	textEdits := []lsp.TextEdit{}
+	textEdits, err := formatter.FormatRange(f.Text, params, s.getConfig())
+	if err != nil {
+		return nil, err
+	}
+	if len(textEdits) > 0 {
+		return textEdits, nil
+	}
+	return nil, nil
+}
+
+func (s *Server) handleTextDocumentOnTypeFormatting(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentOnTypeFormattingParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	textEdits, err := formatter.FormatOnType(f.Text, params, s.getConfig())
+	if err != nil {
+		return nil, err
+	}
 	if len(textEdits) > 0 {
 		return textEdits, nil
 	}
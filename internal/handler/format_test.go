@@ -179,6 +179,91 @@ func TestFormattingWithOptionUpper(t *testing.T) {
 	testFormatting(t, testCase, formattingOptionTab, upperCaseConfig)
 }
 
+func TestRangeFormatting(t *testing.T) {
+	tx := newTestContext()
+	tx.initServer(t)
+	defer tx.tearDown()
+
+	input := "select 1;\nselect a  from b;\nselect 3"
+	tx.textDocumentDidOpen(t, testFileURI, input)
+
+	params := lsp.DocumentRangeFormattingParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+		Range: lsp.Range{
+			Start: lsp.Position{Line: 1, Character: 2},
+			End:   lsp.Position{Line: 1, Character: 4},
+		},
+		Options: formattingOptionIndentSpace2,
+	}
+	var got []lsp.TextEdit
+	if err := tx.conn.Call(tx.ctx, "textDocument/rangeFormatting", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/rangeFormatting:", err)
+	}
+	want := []lsp.TextEdit{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 17},
+			},
+			NewText: "SELECT\n  a\nFROM\n  b;",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch (- want, + got):\n%s", diff)
+	}
+}
+
+func TestOnTypeFormatting(t *testing.T) {
+	tx := newTestContext()
+	tx.initServer(t)
+	defer tx.tearDown()
+
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT a\n  from b\n")
+
+	params := lsp.DocumentOnTypeFormattingParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{
+				URI: testFileURI,
+			},
+			Position: lsp.Position{Line: 2, Character: 0},
+		},
+		Ch:      "\n",
+		Options: formattingOptionTab,
+	}
+	var got []lsp.TextEdit
+	if err := tx.conn.Call(tx.ctx, "textDocument/onTypeFormatting", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/onTypeFormatting:", err)
+	}
+	want := []lsp.TextEdit{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 2},
+			},
+			NewText: "",
+		},
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 2},
+				End:   lsp.Position{Line: 1, Character: 6},
+			},
+			NewText: "FROM",
+		},
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 2, Character: 0},
+				End:   lsp.Position{Line: 2, Character: 0},
+			},
+			NewText: "\t",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch (- want, + got):\n%s", diff)
+	}
+}
+
 func loadFormatTestCaseByTestdata(targetDir string) ([]formattingTestCase, error) {
 	packageDir, err := os.Getwd()
 	if err != nil {
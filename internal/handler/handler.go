@@ -6,10 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/sourcegraph/jsonrpc2"
 
+	"github.com/sqls-server/sqls/dialect"
 	"github.com/sqls-server/sqls/internal/config"
 	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
@@ -23,8 +28,17 @@ type Server struct {
 	SpecificFileCfg *config.Config
 	DefaultFileCfg  *config.Config
 	WSCfg           *config.Config
+	// cfgMu guards WSCfg and initOptionDBConfig, which the client sets
+	// while the requests are handled.
+	cfgMu sync.RWMutex
+
+	// Caches shares the database caches with the other servers of the
+	// process. If nil, the caches are not shared.
+	Caches *database.CacheStore
 
 	dbConn *database.DBConnection
+	// dbConnMu guards dbConn for the requests not holding connMu.
+	dbConnMu sync.RWMutex
 
 	curDBCfg           *database.DBConfig
 	curDBName          string
@@ -36,22 +50,66 @@ type Server struct {
 	// other configuration sources (workspace and user).
 	initOptionDBConfig *database.DBConfig
 
-	worker *database.Worker
-	files  map[string]*File
+	// rootPath is the directory of the workspace, where the output files of
+	// the commands are written.
+	rootPath string
+	// codeActionLiterals is set if the client accepts CodeAction literals in
+	// reply to textDocument/codeAction, and not only commands.
+	codeActionLiterals bool
+
+	worker  *database.Worker
+	files   map[string]*File
+	filesMu sync.RWMutex
+
+	// cacheProgress reports the loading of the database cache to clients
+	// supporting work done progress.
+	cacheProgress database.CacheProgressFunc
+
+	// ctx is the context of the background tasks, cancelled on shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// connecting counts the connections made in the background.
+	connecting sync.WaitGroup
+
+	// connMu serializes the use of the database connection by the long
+	// running requests, which are handled outside of the dispatch loop.
+	connMu sync.Mutex
+	// cursor is the query truncated by the last execution, guarded by connMu.
+	cursor    *queryCursor
+	cursorSeq int
+
+	requestsMu sync.Mutex
+	requests   map[jsonrpc2.ID]context.CancelFunc
+
+	// statements are the statements of executeQuery running, for cancelQuery.
+	statementsMu sync.Mutex
+	statements   map[*statement]struct{}
+
+	// diagnosticsTimers are the pending publications of diagnostics by URI.
+	diagnosticsMu     sync.Mutex
+	diagnosticsTimers map[string]*time.Timer
 }
 
 type File struct {
 	LanguageID string
 	Text       string
+	Version    int
 }
 
 func NewServer() *Server {
 	worker := database.NewWorker()
 	worker.Start()
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		files:  make(map[string]*File),
-		worker: worker,
+		files:      make(map[string]*File),
+		worker:     worker,
+		ctx:        ctx,
+		cancel:     cancel,
+		requests:   make(map[jsonrpc2.ID]context.CancelFunc),
+		statements: make(map[*statement]struct{}),
+
+		diagnosticsTimers: make(map[string]*time.Timer),
 	}
 }
 
@@ -69,6 +127,12 @@ func panicf(r interface{}, format string, v ...interface{}) error {
 }
 
 func (s *Server) Stop() error {
+	s.cancel()
+	s.diagnosticsMu.Lock()
+	for _, timer := range s.diagnosticsTimers {
+		timer.Stop()
+	}
+	s.diagnosticsMu.Unlock()
 	if err := s.dbConn.Close(); err != nil {
 		return err
 	}
@@ -84,6 +148,9 @@ func (s *Server) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 		}
 	}()
 	res, err := s.handle(ctx, conn, req)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		err = &jsonrpc2.Error{Code: lsp.CodeRequestCancelled, Message: "request cancelled"}
+	}
 	if err != nil {
 		log.Printf("error serving, %+v\n", err)
 	}
@@ -94,7 +161,9 @@ func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 	case "initialize":
 		return s.handleInitialize(ctx, conn, req)
 	case "initialized":
-		return
+		return s.handleInitialized(ctx, conn, req)
+	case "$/cancelRequest":
+		return s.handleCancelRequest(ctx, conn, req)
 	case "shutdown":
 		return s.handleShutdown(ctx, conn, req)
 	case "exit":
@@ -121,16 +190,44 @@ func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 		return s.handleTextDocumentFormatting(ctx, conn, req)
 	case "textDocument/rangeFormatting":
 		return s.handleTextDocumentRangeFormatting(ctx, conn, req)
+	case "textDocument/onTypeFormatting":
+		return s.handleTextDocumentOnTypeFormatting(ctx, conn, req)
 	case "textDocument/signatureHelp":
 		return s.handleTextDocumentSignatureHelp(ctx, conn, req)
+	case "textDocument/prepareRename":
+		return s.handleTextDocumentPrepareRename(ctx, conn, req)
 	case "textDocument/rename":
 		return s.handleTextDocumentRename(ctx, conn, req)
 	case "textDocument/definition":
 		return s.handleDefinition(ctx, conn, req)
 	case "textDocument/typeDefinition":
 		return s.handleDefinition(ctx, conn, req)
+	case "textDocument/documentSymbol":
+		return s.handleTextDocumentDocumentSymbol(ctx, conn, req)
+	case "textDocument/documentHighlight":
+		return s.handleTextDocumentDocumentHighlight(ctx, conn, req)
+	case "textDocument/foldingRange":
+		return s.handleTextDocumentFoldingRange(ctx, conn, req)
+	case "textDocument/selectionRange":
+		return s.handleTextDocumentSelectionRange(ctx, conn, req)
+	case "textDocument/semanticTokens/full":
+		return s.handleTextDocumentSemanticTokensFull(ctx, conn, req)
+	case "textDocument/semanticTokens/range":
+		return s.handleTextDocumentSemanticTokensRange(ctx, conn, req)
+	case "textDocument/inlayHint":
+		return s.handleTextDocumentInlayHint(ctx, conn, req)
+	case "textDocument/codeLens":
+		return s.handleTextDocumentCodeLens(ctx, conn, req)
+	case "textDocument/references":
+		return s.handleTextDocumentReferences(ctx, conn, req)
+	case "workspace/symbol":
+		return s.handleWorkspaceSymbol(ctx, conn, req)
+	case "workspace/textDocumentContent":
+		return s.handleWorkspaceTextDocumentContent(ctx, conn, req)
 	case "window/showMessage":
 		return
+	case "textDocument/publishDiagnostics":
+		return
 	}
 	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", req.Method)}
 }
@@ -147,9 +244,11 @@ func (s *Server) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 
 	result = lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync:   lsp.TDSKFull,
-			HoverProvider:      true,
-			CodeActionProvider: true,
+			TextDocumentSync: lsp.TDSKIncremental,
+			HoverProvider:    true,
+			CodeActionProvider: &lsp.CodeActionOptions{
+				CodeActionKinds: []lsp.CodeActionKind{lsp.CAKQuickFix, lsp.CAKSource},
+			},
 			CompletionProvider: &lsp.CompletionOptions{
 				TriggerCharacters: []string{"(", "."},
 			},
@@ -163,32 +262,70 @@ func (s *Server) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 			DefinitionProvider:              true,
 			DocumentFormattingProvider:      true,
 			DocumentRangeFormattingProvider: true,
-			RenameProvider:                  true,
+			DocumentOnTypeFormattingProvider: &lsp.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: "\n",
+				MoreTriggerCharacter:  []string{";", ")"},
+			},
+			RenameProvider:            &lsp.RenameOptions{PrepareProvider: true},
+			DocumentHighlightProvider: true,
+			ReferencesProvider:        true,
+			DocumentSymbolProvider:    true,
+			WorkspaceSymbolProvider:   true,
+			FoldingRangeProvider:      true,
+			SelectionRangeProvider:    true,
+			SemanticTokensProvider: &lsp.SemanticTokensOptions{
+				Legend: semanticTokenLegend,
+				Range:  true,
+				Full:   true,
+			},
+			InlayHintProvider: true,
+			CodeLensProvider:  &lsp.CodeLensOptions{},
+			Workspace: &lsp.WorkspaceOptions{
+				TextDocumentContent: &lsp.TextDocumentContentOptions{
+					Schemes: []string{tableDocScheme},
+				},
+			},
 		},
 	}
 
+	s.cfgMu.Lock()
 	s.initOptionDBConfig = params.InitializationOptions.ConnectionConfig
+	s.rootPath = rootPath(params)
+	s.codeActionLiterals = params.Capabilities.TextDocument.CodeAction.CodeActionLiteralSupport != nil
+	s.cfgMu.Unlock()
+	if params.Capabilities.Window.WorkDoneProgress {
+		s.cacheProgress = newCacheProgressFunc(s.ctx, conn)
+	}
+	return result, nil
+}
 
+// rootPath returns the directory of the workspace of the client, or an empty
+// string if it has none.
+func rootPath(params lsp.InitializeParams) string {
+	if params.RootURI == "" {
+		return params.RootPath
+	}
+	u, err := url.Parse(params.RootURI)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (s *Server) handleInitialized(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
 	// Initialize database database connection
+	// It is not made on initialize, for loading the database cache may
+	// request work done progress before the client has the result
 	// NOTE: If no connection is found at this point, it is possible that the connection settings are sent to workspace config, so don't make an error
-	messenger := lsp.NewMessenger(conn)
-	if err := s.reconnectionDB(ctx); err != nil {
-		if !errors.Is(ErrNoConnection, err) {
-			if err := messenger.ShowInfo(ctx, err.Error()); err != nil {
-				log.Println("send info", err.Error())
-				return nil, err
-			}
-		} else {
-			log.Println("send err", err.Error())
-			if err := messenger.ShowError(ctx, err.Error()); err != nil {
-				return nil, err
-			}
-		}
-	}
-	return result, nil
+	s.connectDB(conn)
+	return nil, nil
 }
 
 func (s *Server) handleShutdown(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	s.cancelRequests()
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.closeCursor()
 	if s.dbConn != nil {
 		s.dbConn.Close()
 	}
@@ -196,6 +333,10 @@ func (s *Server) handleShutdown(ctx context.Context, conn *jsonrpc2.Conn, req *j
 }
 
 func (s *Server) handleExit(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	s.cancelRequests()
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.closeCursor()
 	if s.dbConn != nil {
 		s.dbConn.Close()
 	}
@@ -213,12 +354,13 @@ func (s *Server) handleTextDocumentDidOpen(ctx context.Context, conn *jsonrpc2.C
 		return nil, err
 	}
 
-	if err := s.openFile(params.TextDocument.URI, params.TextDocument.LanguageID); err != nil {
+	if err := s.openFile(params.TextDocument.URI, params.TextDocument.LanguageID, params.TextDocument.Version); err != nil {
 		return nil, err
 	}
 	if err := s.updateFile(params.TextDocument.URI, params.TextDocument.Text); err != nil {
 		return nil, err
 	}
+	s.scheduleDiagnostics(conn, params.TextDocument.URI, 0)
 	return nil, nil
 }
 
@@ -232,9 +374,11 @@ func (s *Server) handleTextDocumentDidChange(ctx context.Context, conn *jsonrpc2
 		return nil, err
 	}
 
-	if err := s.updateFile(params.TextDocument.URI, params.ContentChanges[0].Text); err != nil {
+	if err := s.changeFile(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges); err != nil {
 		return nil, err
 	}
+	// Checking the document on every keystroke would hold the dispatch loop
+	s.scheduleDiagnostics(conn, params.TextDocument.URI, diagnosticsDelay)
 	return nil, nil
 }
 
@@ -256,6 +400,7 @@ func (s *Server) handleTextDocumentDidSave(ctx context.Context, conn *jsonrpc2.C
 	if err != nil {
 		return nil, err
 	}
+	s.scheduleDiagnostics(conn, params.TextDocument.URI, 0)
 	return nil, nil
 }
 
@@ -272,29 +417,88 @@ func (s *Server) handleTextDocumentDidClose(ctx context.Context, conn *jsonrpc2.
 	if err := s.closeFile(params.TextDocument.URI); err != nil {
 		return nil, err
 	}
+	s.cancelDiagnostics(params.TextDocument.URI)
+	if err := s.clearDiagnostics(ctx, conn, params.TextDocument.URI); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
-func (s *Server) openFile(uri string, languageID string) error {
+// file returns the document at uri. Documents are replaced instead of being
+// modified, so the returned file can be read while the document changes.
+func (s *Server) file(uri string) (*File, bool) {
+	s.filesMu.RLock()
+	defer s.filesMu.RUnlock()
+	f, ok := s.files[uri]
+	return f, ok
+}
+
+// openFiles returns the open documents by URI.
+func (s *Server) openFiles() map[string]*File {
+	s.filesMu.RLock()
+	defer s.filesMu.RUnlock()
+	files := make(map[string]*File, len(s.files))
+	for uri, f := range s.files {
+		files[uri] = f
+	}
+	return files
+}
+
+func (s *Server) openFile(uri string, languageID string, version int) error {
 	f := &File{
 		Text:       "",
 		LanguageID: languageID,
+		Version:    version,
 	}
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	s.files[uri] = f
 	return nil
 }
 
 func (s *Server) closeFile(uri string) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	delete(s.files, uri)
 	return nil
 }
 
 func (s *Server) updateFile(uri string, text string) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	f, ok := s.files[uri]
 	if !ok {
 		return fmt.Errorf("document not found: %v", uri)
 	}
-	f.Text = text
+	updated := *f
+	updated.Text = text
+	s.files[uri] = &updated
+	return nil
+}
+
+func (s *Server) changeFile(uri string, version int, changes []lsp.TextDocumentContentChangeEvent) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+	f, ok := s.files[uri]
+	if !ok {
+		return fmt.Errorf("document not found: %v", uri)
+	}
+	if version < f.Version {
+		return fmt.Errorf("stale document version %d, current version %d: %v", version, f.Version, uri)
+	}
+
+	text := f.Text
+	for _, change := range changes {
+		var err error
+		text, err = applyContentChange(text, change)
+		if err != nil {
+			return err
+		}
+	}
+	updated := *f
+	updated.Text = text
+	updated.Version = version
+	s.files[uri] = &updated
 	return nil
 }
 
@@ -308,33 +512,53 @@ func (s *Server) handleWorkspaceDidChangeConfiguration(ctx context.Context, conn
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		return nil, err
 	}
+	s.cfgMu.Lock()
 	s.WSCfg = params.Settings.SQLS
-
-	// Skip database connection
-	if s.dbConn != nil {
-		return nil, nil
-	}
+	s.cfgMu.Unlock()
 
 	// Initialize database database connection
-	messenger := lsp.NewMessenger(conn)
-	if err := s.reconnectionDB(ctx); err != nil {
-		if !errors.Is(ErrNoConnection, err) {
-			if err := messenger.ShowInfo(ctx, err.Error()); err != nil {
-				log.Println("send info", err.Error())
-				return nil, err
-			}
-		} else {
-			log.Println("send err", err.Error())
-			if err := messenger.ShowError(ctx, err.Error()); err != nil {
-				return nil, err
-			}
+	s.connectDB(conn)
+	return nil, nil
+}
+
+// connectDB connects to the database unless it is connected, outside of the
+// dispatch loop so that loading the metadata of a large database does not
+// hold up the client. Completion goes without the database cache until it is
+// loaded.
+func (s *Server) connectDB(conn *jsonrpc2.Conn) {
+	s.connecting.Add(1)
+	go func() {
+		defer s.connecting.Done()
+		s.connMu.Lock()
+		defer s.connMu.Unlock()
+		if s.dbConn != nil {
+			return
 		}
-	}
 
-	return nil, nil
+		ctx := s.ctx
+		messenger := lsp.NewMessenger(conn)
+		if err := s.reconnectionDB(ctx, false); err != nil {
+			if !errors.Is(ErrNoConnection, err) {
+				if err := messenger.ShowInfo(ctx, err.Error()); err != nil {
+					log.Println("send info", err.Error())
+				}
+			} else {
+				log.Println("send err", err.Error())
+				if err := messenger.ShowError(ctx, err.Error()); err != nil {
+					log.Println("send err", err.Error())
+				}
+			}
+			return
+		}
+		s.republishDiagnostics(conn)
+	}()
 }
 
-func (s *Server) reconnectionDB(ctx context.Context) error {
+// reconnectionDB connects to the current database again. The cache shared by
+// the other servers is used unless reload is set, in which case the cache is
+// loaded again and shared in place of it.
+func (s *Server) reconnectionDB(ctx context.Context, reload bool) error {
+	s.closeCursor()
 	if err := s.dbConn.Close(); err != nil {
 		return err
 	}
@@ -343,17 +567,35 @@ func (s *Server) reconnectionDB(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	s.dbConnMu.Lock()
 	s.dbConn = dbConn
+	s.dbConnMu.Unlock()
 	dbRepo, err := s.newDBRepository(ctx)
 	if err != nil {
 		return err
 	}
-	if err := s.worker.ReCache(ctx, dbRepo); err != nil {
+	if ref := s.Caches.Load(s.curDBCfg); ref != nil && !reload {
+		s.worker.UseCache(dbRepo, ref)
+		return nil
+	}
+	if err := s.worker.ReCache(ctx, dbRepo, s.cacheProgress); err != nil {
 		return err
 	}
+	s.Caches.Store(s.curDBCfg, s.worker.CacheRef())
 	return nil
 }
 
+// driver returns the driver of the database connection, or an empty driver
+// if it is not connected.
+func (s *Server) driver() dialect.DatabaseDriver {
+	s.dbConnMu.RLock()
+	defer s.dbConnMu.RUnlock()
+	if s.dbConn == nil {
+		return ""
+	}
+	return s.dbConn.Driver
+}
+
 func (s *Server) newDBConnection(ctx context.Context) (*database.DBConnection, error) {
 	// Get the most preferred DB connection settings
 	connCfg := s.topConnection()
@@ -389,8 +631,11 @@ func (s *Server) newDBRepository(ctx context.Context) (database.DBRepository, er
 
 func (s *Server) topConnection() *database.DBConfig {
 	// if the init config is set, ignore all other connection configs
-	if s.initOptionDBConfig != nil {
-		return s.initOptionDBConfig
+	s.cfgMu.RLock()
+	initOptionDBConfig := s.initOptionDBConfig
+	s.cfgMu.RUnlock()
+	if initOptionDBConfig != nil {
+		return initOptionDBConfig
 	}
 
 	cfg := s.getConfig()
@@ -409,6 +654,8 @@ func (s *Server) getConnection(index int) *database.DBConfig {
 }
 
 func (s *Server) getConfig() *config.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
 	var cfg *config.Config
 	switch {
 	case validConfig(s.SpecificFileCfg):
@@ -11,6 +11,7 @@ import (
 	"github.com/sourcegraph/jsonrpc2"
 
 	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
 )
 
@@ -22,11 +23,15 @@ type TestContext struct {
 	connServer *jsonrpc2.Conn
 	server     *Server
 	ctx        context.Context
+	// rootURI is the workspace root sent on initialize
+	rootURI string
+	// capabilities are the client capabilities sent on initialize
+	capabilities lsp.ClientCapabilities
 }
 
 func newTestContext() *TestContext {
 	server := NewServer()
-	handler := jsonrpc2.HandlerWithError(server.Handle)
+	handler := server.Handler()
 	ctx := context.Background()
 	return &TestContext{
 		h:      handler,
@@ -66,11 +71,17 @@ func (tx *TestContext) initServer(t *testing.T) {
 
 	// Initialize Language Server
 	params := lsp.InitializeParams{
+		RootURI:               tx.rootURI,
 		InitializationOptions: lsp.InitializeOptions{},
+		Capabilities:          tx.capabilities,
 	}
 	if err := tx.conn.Call(tx.ctx, "initialize", params, nil); err != nil {
 		t.Fatal("conn.Call initialize:", err)
 	}
+	if err := tx.conn.Call(tx.ctx, "initialized", struct{}{}, nil); err != nil {
+		t.Fatal("conn.Call initialized:", err)
+	}
+	tx.server.connecting.Wait()
 }
 
 func (tx *TestContext) addWorkspaceConfig(t *testing.T, cfg *config.Config) {
@@ -84,6 +95,7 @@ func (tx *TestContext) addWorkspaceConfig(t *testing.T, cfg *config.Config) {
 	if err := tx.conn.Call(tx.ctx, "workspace/didChangeConfiguration", didChangeConfigurationParams, nil); err != nil {
 		t.Fatal("conn.Call workspace/didChangeConfiguration:", err)
 	}
+	tx.server.connecting.Wait()
 }
 
 func (tx *TestContext) textDocumentDidOpen(t *testing.T, uri, input string) {
@@ -108,7 +120,7 @@ func TestInitialized(t *testing.T) {
 
 	want := lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync: lsp.TDSKFull,
+			TextDocumentSync: lsp.TDSKIncremental,
 			HoverProvider:    true,
 			CompletionProvider: &lsp.CompletionOptions{
 				TriggerCharacters: []string{"(", "."},
@@ -120,11 +132,35 @@ func TestInitialized(t *testing.T) {
 					WorkDoneProgress: false,
 				},
 			},
-			CodeActionProvider:              true,
+			CodeActionProvider: &lsp.CodeActionOptions{
+				CodeActionKinds: []lsp.CodeActionKind{lsp.CAKQuickFix, lsp.CAKSource},
+			},
 			DefinitionProvider:              true,
 			DocumentFormattingProvider:      true,
 			DocumentRangeFormattingProvider: true,
-			RenameProvider:                  true,
+			DocumentOnTypeFormattingProvider: &lsp.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: "\n",
+				MoreTriggerCharacter:  []string{";", ")"},
+			},
+			RenameProvider:            &lsp.RenameOptions{PrepareProvider: true},
+			DocumentHighlightProvider: true,
+			ReferencesProvider:        true,
+			DocumentSymbolProvider:    true,
+			WorkspaceSymbolProvider:   true,
+			FoldingRangeProvider:      true,
+			SelectionRangeProvider:    true,
+			SemanticTokensProvider: &lsp.SemanticTokensOptions{
+				Legend: semanticTokenLegend,
+				Range:  true,
+				Full:   true,
+			},
+			InlayHintProvider: true,
+			CodeLensProvider:  &lsp.CodeLensOptions{},
+			Workspace: &lsp.WorkspaceOptions{
+				TextDocumentContent: &lsp.TextDocumentContentOptions{
+					Schemes: []string{"sqls"},
+				},
+			},
 		},
 	}
 	var got lsp.InitializeResult
@@ -168,25 +204,74 @@ func TestFileWatch(t *testing.T) {
 		},
 		ContentChanges: []lsp.TextDocumentContentChangeEvent{
 			lsp.TextDocumentContentChangeEvent{
-				Range: lsp.Range{
+				Text: changeText,
+			},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", didChangeParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didChange:", err)
+	}
+	tx.testFile(t, didChangeParams.TextDocument.URI, didChangeParams.ContentChanges[0].Text)
+
+	incrementalChangeParams := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			URI:     uri,
+			Version: 2,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			lsp.TextDocumentContentChangeEvent{
+				Range: &lsp.Range{
 					Start: lsp.Position{
-						Line:      1,
-						Character: 1,
+						Line:      0,
+						Character: 28,
 					},
 					End: lsp.Position{
-						Line:      1,
-						Character: 1,
+						Line:      0,
+						Character: 32,
 					},
 				},
-				RangeLength: 1,
-				Text:        changeText,
+				RangeLength: 4,
+				Text:        "id",
+			},
+			lsp.TextDocumentContentChangeEvent{
+				Range: &lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 31,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 34,
+					},
+				},
+				RangeLength: 3,
+				Text:        "DESC",
 			},
 		},
 	}
-	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", didChangeParams, nil); err != nil {
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", incrementalChangeParams, nil); err != nil {
 		t.Fatal("conn.Call textDocument/didChange:", err)
 	}
-	tx.testFile(t, didChangeParams.TextDocument.URI, didChangeParams.ContentChanges[0].Text)
+	tx.testFile(t, incrementalChangeParams.TextDocument.URI, "SELECT * FROM todo ORDER BY id DESC")
+	if f, _ := tx.server.file(uri); f.Version != 2 {
+		t.Errorf("unexpected version %d", f.Version)
+	}
+
+	staleChangeParams := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			URI:     uri,
+			Version: 1,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			lsp.TextDocumentContentChangeEvent{
+				Text: changeText,
+			},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", staleChangeParams, nil); err == nil {
+		t.Error("expected error for stale version")
+	}
+	tx.testFile(t, uri, "SELECT * FROM todo ORDER BY id DESC")
 
 	didSaveParams := lsp.DidSaveTextDocumentParams{
 		Text:         openText,
@@ -203,14 +288,14 @@ func TestFileWatch(t *testing.T) {
 	if err := tx.conn.Call(tx.ctx, "textDocument/didClose", didCloseParams, nil); err != nil {
 		t.Fatal("conn.Call textDocument/didClose:", err)
 	}
-	_, ok := tx.server.files[didCloseParams.TextDocument.URI]
+	_, ok := tx.server.file(didCloseParams.TextDocument.URI)
 	if ok {
 		t.Errorf("found opened file. URI:%s", didCloseParams.TextDocument.URI)
 	}
 }
 
 func (tx *TestContext) testFile(t *testing.T, uri, text string) {
-	f, ok := tx.server.files[uri]
+	f, ok := tx.server.file(uri)
 	if !ok {
 		t.Errorf("not found opened file. URI:%s", uri)
 	}
@@ -218,3 +303,49 @@ func (tx *TestContext) testFile(t *testing.T, uri, text string) {
 		t.Errorf("not match %s. got: %s", text, f.Text)
 	}
 }
+
+func TestSharedCaches(t *testing.T) {
+	caches := database.NewCacheStore()
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{{Driver: "mock"}},
+	}
+
+	tx1 := newTestContext()
+	tx1.server.Caches = caches
+	tx1.setup(t)
+	defer tx1.tearDown()
+	tx1.addWorkspaceConfig(t, cfg)
+
+	tx2 := newTestContext()
+	tx2.server.Caches = caches
+	tx2.setup(t)
+	defer tx2.tearDown()
+	tx2.addWorkspaceConfig(t, cfg)
+
+	if tx1.server.worker.Cache() == nil {
+		t.Fatal("the cache was not loaded")
+	}
+	if tx1.server.worker.CacheRef() != tx2.server.worker.CacheRef() {
+		t.Error("the cache was loaded again for the same database")
+	}
+
+	// Switching to a database explicitly reloads and shares its cache,
+	// even if it is stored
+	params := lsp.ExecuteCommandParams{
+		Command:   CommandSwitchDatabase,
+		Arguments: []interface{}{"world"},
+	}
+	var refs []*database.CacheRef
+	for i := 0; i < 2; i++ {
+		if err := tx2.conn.Call(tx2.ctx, "workspace/executeCommand", params, nil); err != nil {
+			t.Fatal("conn.Call workspace/executeCommand:", err)
+		}
+		refs = append(refs, tx2.server.worker.CacheRef())
+	}
+	if refs[0] == refs[1] {
+		t.Error("the cache was not loaded again on switch")
+	}
+	if got := caches.Load(tx2.server.curDBCfg); got != refs[1] {
+		t.Error("the reloaded cache was not shared")
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentInlayHint(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.InlayHintParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return inlayHints(f.Text, params.Range, s.worker.Cache(), s.getConfig().ColumnTypeHints)
+}
+
+// inlayHints labels the values of INSERT statements with their column names
+// and, if columnTypes is set, the columns of SELECT lists with their types.
+// Only the statements intersecting rng are looked at.
+func inlayHints(text string, rng lsp.Range, dbCache *database.DBCache, columnTypes bool) ([]lsp.InlayHint, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(text)
+	hints := []lsp.InlayHint{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok || !rangesIntersect(lines.nodeRange(stmt), rng) {
+			continue
+		}
+		hints = append(hints, insertValueHints(stmt, lines, rng, dbCache)...)
+		if columnTypes && dbCache != nil {
+			hints = append(hints, columnTypeHints(stmt, dbCache)...)
+		}
+	}
+
+	res := []lsp.InlayHint{}
+	for _, hint := range hints {
+		hint.Position = lines.position(token.Pos{Line: hint.Position.Line, Col: hint.Position.Character})
+		pos := token.Pos{Line: hint.Position.Line, Col: hint.Position.Character}
+		if inRange(pos, rng) {
+			res = append(res, hint)
+		}
+	}
+	sortInlayHints(res)
+	return res, nil
+}
+
+// insertValueHints labels the values of the rows of an INSERT statement
+// intersecting rng.
+func insertValueHints(stmt *ast.Statement, lines textLines, rng lsp.Range, dbCache *database.DBCache) []lsp.InlayHint {
+	hints := []lsp.InlayHint{}
+	rows := parseutil.ExtractAllInsertValues(stmt)
+	if len(rows) == 0 {
+		return hints
+	}
+	// The table and the columns are the same for every row, so they are
+	// extracted once from the statement alone
+	insert, err := parseutil.ExtractInsert(&ast.Query{Toks: []ast.Node{stmt}}, rows[0].Pos())
+	if err != nil || !insert.Enable() {
+		return hints
+	}
+
+	tableName := insert.GetTable().Name
+	cols := insert.GetColumns().GetIdentifiers()
+	for _, row := range rows {
+		values, ok := row.(*ast.IdentifierList)
+		if !ok || !rangesIntersect(lines.nodeRange(values), rng) {
+			continue
+		}
+		for i, value := range values.GetIdentifiers() {
+			if i >= len(cols) {
+				break
+			}
+			colName := cols[i].String()
+			hint := lsp.InlayHint{
+				Position: lsp.Position{
+					Line:      value.Pos().Line,
+					Character: value.Pos().Col,
+				},
+				Label:        colName + ":",
+				Kind:         lsp.IHKParameter,
+				PaddingRight: true,
+			}
+			if dbCache != nil {
+				if colDesc, ok := dbCache.Column(tableName, colName); ok {
+					hint.Tooltip = colDesc.OnelineDesc()
+				}
+			}
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+func columnTypeHints(stmt *ast.Statement, dbCache *database.DBCache) []lsp.InlayHint {
+	ss := newStatementScopes(stmt)
+	hints := []lsp.InlayHint{}
+	for _, expr := range selectColumns(stmt) {
+		var ident *ast.Identifier
+		switch v := expr.(type) {
+		case *ast.Identifier:
+			ident = v
+		case *ast.MemberIdentifier:
+			ident = v.ChildIdent
+		}
+		if ident == nil || ident.IsWildcard() {
+			continue
+		}
+		col := ss.columnAt(ident)
+		if col == nil {
+			continue
+		}
+		colDesc, ok := columnDesc(ss, col, ident, dbCache)
+		if !ok {
+			continue
+		}
+		hints = append(hints, lsp.InlayHint{
+			Position: lsp.Position{
+				Line:      expr.End().Line,
+				Character: expr.End().Col,
+			},
+			Label:   ": " + colDesc.Type,
+			Kind:    lsp.IHKType,
+			Tooltip: colDesc.OnelineDesc(),
+		})
+	}
+	return hints
+}
+
+// selectColumns returns the expressions of the SELECT lists of the
+// statement, without their aliases.
+func selectColumns(stmt *ast.Statement) []ast.Node {
+	exprs := []ast.Node{}
+	for _, node := range parseutil.ExtractSelectExpr(stmt) {
+		items := []ast.Node{node}
+		if list, ok := node.(*ast.IdentifierList); ok {
+			items = list.GetIdentifiers()
+		}
+		for _, item := range items {
+			if aliased, ok := item.(*ast.Aliased); ok {
+				item = aliased.RealName
+			}
+			exprs = append(exprs, item)
+		}
+	}
+	return exprs
+}
+
+// columnDesc looks up col in the tables that it may belong to, the nearest
+// scope first.
+func columnDesc(ss *statementScopes, col *column, ident *ast.Identifier, dbCache *database.DBCache) (*database.ColumnDesc, bool) {
+	refs := []*parseutil.TableRef{}
+	if col.ref != nil {
+		refs = append(refs, col.ref)
+	} else {
+		for sc := ss.innermostScope(ident); sc != nil; sc = sc.parent {
+			refs = append(refs, sc.refs...)
+		}
+	}
+	for _, ref := range refs {
+		if ref.Ident == nil || ss.refSymbol(ref).kind != tableSymbolKind {
+			continue
+		}
+		if ref.Info.DatabaseSchema == "" {
+			if colDesc, ok := dbCache.Column(ref.Info.Name, col.name); ok {
+				return colDesc, true
+			}
+			continue
+		}
+		cols, _ := dbCache.ColumnDatabase(ref.Info.DatabaseSchema, ref.Info.Name)
+		for _, colDesc := range cols {
+			if strings.EqualFold(colDesc.Name, col.name) {
+				return colDesc, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func sortInlayHints(hints []lsp.InlayHint) {
+	sort.SliceStable(hints, func(i, j int) bool {
+		a := token.Pos{Line: hints[i].Position.Line, Col: hints[i].Position.Character}
+		b := token.Pos{Line: hints[j].Position.Line, Col: hints[j].Position.Character}
+		return token.ComparePos(a, b) < 0
+	})
+}
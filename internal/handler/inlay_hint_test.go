@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestInlayHints(t *testing.T) {
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wholeDocument := lsp.Range{
+		End: lsp.Position{Line: 100},
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		rng         lsp.Range
+		columnTypes bool
+		want        []string
+	}{
+		{
+			name:  "insert values",
+			input: "INSERT INTO city (ID, Name) VALUES (1, 'a'), (2, 'b')",
+			rng:   wholeDocument,
+			want: []string{
+				"0:36 ID: `int(11)` PRI auto_increment",
+				"0:39 Name: `char(35)`",
+				"0:46 ID: `int(11)` PRI auto_increment",
+				"0:49 Name: `char(35)`",
+			},
+		},
+		{
+			name:  "more values than columns",
+			input: "INSERT INTO city (ID, Name) VALUES (1, 'a', 3)",
+			rng:   wholeDocument,
+			want: []string{
+				"0:36 ID: `int(11)` PRI auto_increment",
+				"0:39 Name: `char(35)`",
+			},
+		},
+		{
+			name:        "column types",
+			input:       "SELECT c.ID, Name AS n, Foo, COUNT(*) FROM city c",
+			rng:         wholeDocument,
+			columnTypes: true,
+			want: []string{
+				"0:11 : int(11) `int(11)` PRI auto_increment",
+				"0:17 : char(35) `char(35)`",
+			},
+		},
+		{
+			name:  "column types disabled",
+			input: "SELECT c.ID, Name AS n FROM city c",
+			rng:   wholeDocument,
+			want:  []string{},
+		},
+		{
+			name:  "range",
+			input: "INSERT INTO city (ID, Name) VALUES (1, 'a');\nINSERT INTO city (ID, Name) VALUES (2, 'b')",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 38},
+			},
+			want: []string{
+				"1:36 ID: `int(11)` PRI auto_increment",
+			},
+		},
+		{
+			name:  "rows in the range",
+			input: "INSERT INTO city (ID, Name) VALUES\n(1, 'a'),\n(2, 'b'),\n\t(3, 'c')",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 2, Character: 0},
+				End:   lsp.Position{Line: 4, Character: 0},
+			},
+			want: []string{
+				"2:1 ID: `int(11)` PRI auto_increment",
+				"2:4 Name: `char(35)`",
+				"3:2 ID: `int(11)` PRI auto_increment",
+				"3:5 Name: `char(35)`",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints, err := inlayHints(tt.input, tt.rng, dbCache, tt.columnTypes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, h := range hints {
+				got = append(got, fmt.Sprintf("%d:%d %s %s", h.Position.Line, h.Position.Character, h.Label, h.Tooltip))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch inlay hints (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/token"
+)
+
+// textLines converts the positions of the tokenizer, which counts a tab as
+// several columns, to the UTF-16 positions of LSP.
+type textLines []string
+
+func newTextLines(text string) textLines {
+	return strings.Split(text, "\n")
+}
+
+func (l textLines) position(pos token.Pos) lsp.Position {
+	line := ""
+	if pos.Line >= 0 && pos.Line < len(l) {
+		line = l[pos.Line]
+	}
+	return lsp.Position{Line: pos.Line, Character: token.UTF16Col(line, pos.Col)}
+}
+
+// tokenPos converts an LSP position to the position of the tokenizer.
+func (l textLines) tokenPos(pos lsp.Position) token.Pos {
+	line := ""
+	if pos.Line >= 0 && pos.Line < len(l) {
+		line = l[pos.Line]
+	}
+	return token.Pos{Line: pos.Line, Col: token.ColOfUTF16(line, pos.Character)}
+}
+
+func (l textLines) posRange(from, to token.Pos) lsp.Range {
+	return lsp.Range{
+		Start: l.position(from),
+		End:   l.position(to),
+	}
+}
+
+func (l textLines) nodeRange(node ast.Node) lsp.Range {
+	return l.posRange(node.Pos(), node.End())
+}
+
+// lineRanges splits the range from from to to into a range per line, leaving
+// out the empty ones.
+func (l textLines) lineRanges(from, to token.Pos) []lsp.Range {
+	start, end := l.position(from), l.position(to)
+	ranges := []lsp.Range{}
+	for line := start.Line; line <= end.Line; line++ {
+		rng := lsp.Range{
+			Start: lsp.Position{Line: line},
+			End:   lsp.Position{Line: line, Character: end.Character},
+		}
+		if line == start.Line {
+			rng.Start.Character = start.Character
+		}
+		if line != end.Line && line < len(l) {
+			rng.End.Character = 0
+			for _, r := range strings.TrimSuffix(l[line], "\r") {
+				rng.End.Character += utf16Len(r)
+			}
+		}
+		if rng.End.Character > rng.Start.Character {
+			ranges = append(ranges, rng)
+		}
+	}
+	return ranges
+}
+
+// convertRange converts a range built from the positions of the tokenizer.
+func (l textLines) convertRange(rng lsp.Range) lsp.Range {
+	return l.posRange(
+		token.Pos{Line: rng.Start.Line, Col: rng.Start.Character},
+		token.Pos{Line: rng.End.Line, Col: rng.End.Character},
+	)
+}
+
+// utf16Offset returns the byte offset of the UTF-16 column col in line,
+// clamped to the line.
+func utf16Offset(line string, col int) int {
+	u := 0
+	for i, r := range line {
+		if u >= col {
+			return i
+		}
+		if r >= 0x10000 {
+			u += 2
+		} else {
+			u++
+		}
+	}
+	return len(line)
+}
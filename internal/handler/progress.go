@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// progressCreateTimeout bounds the wait for the client to create a work done
+// progress, as the cache is loaded holding the database connection.
+const progressCreateTimeout = 5 * time.Second
+
+// newCacheProgressFunc returns the reporter of the loading of the database
+// cache as work done progress of conn. The progress is not reported if the
+// client does not create it in time, or ctx is done.
+func newCacheProgressFunc(ctx context.Context, conn *jsonrpc2.Conn) database.CacheProgressFunc {
+	var seq int64
+	return func(title string) database.CacheProgress {
+		token := fmt.Sprintf("sqls/cache/%d", atomic.AddInt64(&seq, 1))
+		ctx, cancel := context.WithTimeout(ctx, progressCreateTimeout)
+		defer cancel()
+		progress, err := lsp.NewWorkDoneProgress(ctx, conn, token, title)
+		if err != nil {
+			log.Println("create work done progress:", err)
+			return nil
+		}
+		return &cacheProgress{progress: progress}
+	}
+}
+
+type cacheProgress struct {
+	progress *lsp.WorkDoneProgress
+}
+
+func (p *cacheProgress) Report(phase database.CachePhase, done, total int) {
+	message := fmt.Sprintf("Loading %s", phase)
+	if err := p.progress.Report(context.Background(), message, done*100/total); err != nil {
+		log.Println("report work done progress:", err)
+	}
+}
+
+func (p *cacheProgress) End(err error) {
+	message := "Done"
+	if err != nil {
+		message = err.Error()
+	}
+	if err := p.progress.End(context.Background(), message); err != nil {
+		log.Println("end work done progress:", err)
+	}
+}
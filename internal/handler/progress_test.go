@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// progressRecorder is a client recording the work done progress by token.
+type progressRecorder struct {
+	mu       sync.Mutex
+	progress map[string][]string
+	ended    int
+}
+
+func (r *progressRecorder) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	switch req.Method {
+	case "window/workDoneProgress/create":
+		// Replied in the background, for the server may be writing to the
+		// pipe too
+		go func() {
+			if err := conn.Reply(ctx, req.ID, nil); err != nil {
+				log.Println("reply work done progress create:", err)
+			}
+		}()
+		return
+	case "$/progress":
+	default:
+		return
+	}
+	var params struct {
+		Token string                 `json:"token"`
+		Value map[string]interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		log.Println("unmarshal progress:", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := params.Value
+	var s string
+	switch v["kind"] {
+	case "begin":
+		s = fmt.Sprintf("begin %v", v["title"])
+	case "report":
+		s = fmt.Sprintf("report %v %v%%", v["message"], v["percentage"])
+	case "end":
+		s = fmt.Sprintf("end %v", v["message"])
+		r.ended++
+	}
+	r.progress[params.Token] = append(r.progress[params.Token], s)
+}
+
+func (r *progressRecorder) waitEnded(t *testing.T, n int) map[string][]string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		if r.ended >= n {
+			defer r.mu.Unlock()
+			return r.progress
+		}
+		r.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d progress did not end", n)
+	return nil
+}
+
+func TestCacheProgress(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	recorder := &progressRecorder{progress: map[string][]string{}}
+
+	client, serverPipe := net.Pipe()
+	connServer := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(serverPipe, jsonrpc2.VSCodeObjectCodec{}), server.Handler())
+	defer connServer.Close()
+	conn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), recorder)
+	defer conn.Close()
+
+	params := lsp.InitializeParams{
+		Capabilities: lsp.ClientCapabilities{
+			Window: lsp.WindowClientCapabilities{WorkDoneProgress: true},
+		},
+	}
+	if err := conn.Call(ctx, "initialize", params, nil); err != nil {
+		t.Fatal("conn.Call initialize:", err)
+	}
+	didChangeConfigurationParams := lsp.DidChangeConfigurationParams{
+		Settings: struct {
+			SQLS *config.Config "json:\"sqls\""
+		}{
+			SQLS: &config.Config{
+				Connections: []*database.DBConfig{{Driver: "mock"}},
+			},
+		},
+	}
+	if err := conn.Call(ctx, "workspace/didChangeConfiguration", didChangeConfigurationParams, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeConfiguration:", err)
+	}
+
+	want := map[string][]string{
+		"sqls/cache/1": {
+			"begin Loading the database metadata",
+			"report Loading schemas 0%",
+			"report Loading tables 25%",
+			"report Loading columns 50%",
+			"report Loading foreign keys 75%",
+			"end Done",
+		},
+		"sqls/cache/2": {
+			"begin Loading the columns of all schemas",
+			"report Loading columns 0%",
+			"end Done",
+		},
+	}
+	got := recorder.waitEnded(t, 2)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatched progress (- want, + got):\n%s", diff)
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func (s *Server) handleTextDocumentReferences(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.ReferenceParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.file(params.TextDocument.URI); !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	docs := map[string]string{}
+	for uri, f := range s.openFiles() {
+		docs[uri] = f.Text
+	}
+	return references(docs, params)
+}
+
+// references returns the locations referring to the alias, CTE or table at
+// the position. Aliases and CTEs are looked up in their statement, tables in
+// every document of docs.
+func references(docs map[string]string, params lsp.ReferenceParams) ([]lsp.Location, error) {
+	uri := params.TextDocument.URI
+	stmts, err := getStatements(docs[uri])
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(docs[uri])
+	pos := lines.tokenPos(params.Position)
+	stmt := statementAt(stmts, pos)
+	if stmt == nil {
+		return nil, nil
+	}
+	ident := identifierAt(stmt, pos)
+	if ident == nil {
+		return nil, nil
+	}
+	ss := newStatementScopes(stmt)
+	sym := ss.symbolAt(ident)
+	if sym == nil {
+		return nil, nil
+	}
+
+	locations := []lsp.Location{}
+	if sym.kind != tableSymbolKind {
+		locations = appendOccurrenceLocations(locations, uri, lines, ss.occurrences(sym), params.Context.IncludeDeclaration)
+		return locations, nil
+	}
+
+	uris := []string{}
+	for u := range docs {
+		uris = append(uris, u)
+	}
+	sort.Strings(uris)
+	for _, u := range uris {
+		stmts, err := getStatements(docs[u])
+		if err != nil {
+			// Other documents may be in the middle of editing
+			continue
+		}
+		lines := newTextLines(docs[u])
+		for _, stmt := range stmts {
+			occs := newStatementScopes(stmt).occurrences(sym)
+			locations = appendOccurrenceLocations(locations, u, lines, occs, params.Context.IncludeDeclaration)
+		}
+	}
+	return locations, nil
+}
+
+func appendOccurrenceLocations(locations []lsp.Location, uri string, lines textLines, occs []*occurrence, includeDeclaration bool) []lsp.Location {
+	for _, occ := range occs {
+		if occ.isDefinition && !includeDeclaration {
+			continue
+		}
+		locations = append(locations, lsp.Location{
+			URI:   uri,
+			Range: lines.nodeRange(occ.ident),
+		})
+	}
+	return locations
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestReferences(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	otherURI := "file:///other.sql"
+	otherText := "SELECT city.ID FROM city JOIN world.city c ON c.ID = city.ID"
+	didOpenParams := lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{
+			URI:        otherURI,
+			LanguageID: "sql",
+			Version:    0,
+			Text:       otherText,
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didOpen", didOpenParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didOpen:", err)
+	}
+
+	tests := []struct {
+		name               string
+		input              string
+		pos                lsp.Position
+		includeDeclaration bool
+		want               []string
+	}{
+		{
+			name:               "outer alias",
+			input:              "SELECT c.ID FROM city c WHERE c.ID IN (SELECT c.ID FROM country c)",
+			pos:                lsp.Position{Line: 0, Character: 7},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:7-0:8",
+				"test.sql 0:22-0:23",
+				"test.sql 0:30-0:31",
+			},
+		},
+		{
+			name:               "shadowing alias in sub query",
+			input:              "SELECT c.ID FROM city c WHERE c.ID IN (SELECT c.ID FROM country c)",
+			pos:                lsp.Position{Line: 0, Character: 64},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:46-0:47",
+				"test.sql 0:64-0:65",
+			},
+		},
+		{
+			name:               "correlated sub query",
+			input:              "SELECT * FROM city c WHERE EXISTS (SELECT 1 FROM country co WHERE co.Code = c.CountryCode)",
+			pos:                lsp.Position{Line: 0, Character: 19},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:19-0:20",
+				"test.sql 0:76-0:77",
+			},
+		},
+		{
+			name:               "cte",
+			input:              "WITH x AS (SELECT ID FROM city) SELECT x.ID FROM x JOIN x AS y ON y.ID = x.ID",
+			pos:                lsp.Position{Line: 0, Character: 49},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:5-0:6",
+				"test.sql 0:39-0:40",
+				"test.sql 0:49-0:50",
+				"test.sql 0:56-0:57",
+				"test.sql 0:73-0:74",
+			},
+		},
+		{
+			name:               "cte without declaration",
+			input:              "WITH x AS (SELECT ID FROM city) SELECT x.ID FROM x",
+			pos:                lsp.Position{Line: 0, Character: 5},
+			includeDeclaration: false,
+			want: []string{
+				"test.sql 0:39-0:40",
+				"test.sql 0:49-0:50",
+			},
+		},
+		{
+			name:               "table across documents",
+			input:              "SELECT * FROM city; UPDATE city SET Name = 'x'",
+			pos:                lsp.Position{Line: 0, Character: 14},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:14-0:18",
+				"test.sql 0:27-0:31",
+				"other.sql 0:7-0:11",
+				"other.sql 0:20-0:24",
+				"other.sql 0:36-0:40",
+				"other.sql 0:53-0:57",
+			},
+		},
+		{
+			name:               "alias after a tab",
+			input:              "SELECT\n\tc.ID FROM city c",
+			pos:                lsp.Position{Line: 1, Character: 1},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 1:1-1:2",
+				"test.sql 1:16-1:17",
+			},
+		},
+		{
+			name:               "alias after a surrogate pair",
+			input:              "SELECT '😀', c.ID FROM city c",
+			pos:                lsp.Position{Line: 0, Character: 28},
+			includeDeclaration: true,
+			want: []string{
+				"test.sql 0:13-0:14",
+				"test.sql 0:28-0:29",
+			},
+		},
+		{
+			name:               "not a symbol",
+			input:              "SELECT ID FROM city",
+			pos:                lsp.Position{Line: 0, Character: 7},
+			includeDeclaration: true,
+			want:               []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.ReferenceParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{
+						URI: testFileURI,
+					},
+					Position: tt.pos,
+				},
+				Context: lsp.ReferenceContext{
+					IncludeDeclaration: tt.includeDeclaration,
+				},
+			}
+			var got []lsp.Location
+			if err := tx.conn.Call(tx.ctx, "textDocument/references", params, &got); err != nil {
+				t.Fatal("conn.Call textDocument/references:", err)
+			}
+			gotStrs := []string{}
+			for _, loc := range got {
+				name := loc.URI[strings.LastIndex(loc.URI, "/")+1:]
+				r := loc.Range
+				gotStrs = append(gotStrs, fmt.Sprintf("%s %d:%d-%d:%d", name, r.Start.Line, r.Start.Character, r.End.Line, r.End.Character))
+			}
+			if diff := cmp.Diff(tt.want, gotStrs); diff != "" {
+				t.Errorf("unmatch references (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -7,96 +7,102 @@ import (
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/sqls-server/sqls/ast"
-	"github.com/sqls-server/sqls/ast/astutil"
 	"github.com/sqls-server/sqls/internal/lsp"
-	"github.com/sqls-server/sqls/parser"
-	"github.com/sqls-server/sqls/parser/parseutil"
 	"github.com/sqls-server/sqls/token"
 )
 
-func (s *Server) handleTextDocumentRename(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+func (s *Server) handleTextDocumentPrepareRename(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
 	if req.Params == nil {
 		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
 	}
 
-	var params lsp.RenameParams
+	var params lsp.PrepareRenameParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.file(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	res, err := rename(f.Text, params)
+	res, err := prepareRename(f.Text, params)
 	if err != nil {
 		return nil, err
 	}
+	if res == nil {
+		return nil, nil
+	}
 	return res, nil
 }
 
-func rename(text string, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
-	parsed, err := parser.Parse(text)
-	if err != nil {
+func (s *Server) handleTextDocumentRename(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.RenameParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		return nil, err
 	}
 
-	pos := token.Pos{
-		Line: params.Position.Line,
-		Col:  params.Position.Character,
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	// Get the identifier on focus
-	nodeWalker := parseutil.NewNodeWalker(parsed, pos)
-	m := astutil.NodeMatcher{
-		NodeTypes: []ast.NodeType{ast.TypeIdentifier},
+	res, err := rename(f.Text, f.Version, params)
+	if err != nil {
+		return nil, err
 	}
-	currentVariable := nodeWalker.CurNodeBottomMatched(m)
-	if currentVariable == nil {
+	if res == nil {
 		return nil, nil
 	}
+	return res, nil
+}
 
-	// Get all identifiers in the statement
-	idents, err := parseutil.ExtractIdenfiers(parsed, pos)
+// prepareRename returns the range of the name at the position, or nil if it
+// can not be renamed.
+func prepareRename(text string, params lsp.PrepareRenameParams) (*lsp.PrepareRenameResult, error) {
+	lines := newTextLines(text)
+	pos := lines.tokenPos(params.Position)
+	ident, _, err := renameTarget(text, pos)
 	if err != nil {
 		return nil, err
 	}
+	if ident == nil {
+		return nil, nil
+	}
+	return &lsp.PrepareRenameResult{
+		Range:       lines.nodeRange(ident),
+		Placeholder: ident.String(),
+	}, nil
+}
 
-	// Extract only those with matching names
-	renameTarget := []ast.Node{}
-	for _, ident := range idents {
-		if ident.String() == currentVariable.String() {
-			renameTarget = append(renameTarget, ident)
-		}
+func rename(text string, version int, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
+	lines := newTextLines(text)
+	pos := lines.tokenPos(params.Position)
+	_, occs, err := renameTarget(text, pos)
+	if err != nil {
+		return nil, err
 	}
-	if len(renameTarget) == 0 {
+	if len(occs) == 0 {
 		return nil, nil
 	}
 
-	edits := make([]lsp.TextEdit, len(renameTarget))
-	for i, target := range renameTarget {
-		edit := lsp.TextEdit{
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      target.Pos().Line,
-					Character: target.Pos().Col,
-				},
-				End: lsp.Position{
-					Line:      target.End().Line,
-					Character: target.End().Col,
-				},
-			},
+	edits := make([]lsp.TextEdit, len(occs))
+	for i, occ := range occs {
+		edits[i] = lsp.TextEdit{
+			Range:   lines.nodeRange(occ.ident),
 			NewText: params.NewName,
 		}
-		edits[i] = edit
 	}
 
 	res := &lsp.WorkspaceEdit{
 		DocumentChanges: []lsp.TextDocumentEdit{
 			{
 				TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
-					Version: 0,
+					Version: int32(version),
 					TextDocumentIdentifier: lsp.TextDocumentIdentifier{
 						URI: params.TextDocument.URI,
 					},
@@ -108,3 +114,36 @@ func rename(text string, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
 
 	return res, nil
 }
+
+// renameTarget returns the identifier at pos and the occurrences renamed
+// with it in its statement: the uses of a table alias, a sub query alias, a
+// CTE name or a column alias in their scope, and the occurrences of a table
+// or a column resolved as references and documentHighlight do. Keywords and
+// unknown names, such as a column of an undefined qualifier, give a nil
+// identifier.
+func renameTarget(text string, pos token.Pos) (*ast.Identifier, []*occurrence, error) {
+	stmts, err := getStatements(text)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt := statementAt(stmts, pos)
+	if stmt == nil {
+		return nil, nil, nil
+	}
+	ident := identifierAt(stmt, pos)
+	if ident == nil {
+		return nil, nil, nil
+	}
+
+	ss := newStatementScopes(stmt)
+	if sym := ss.symbolAt(ident); sym != nil {
+		return ident, ss.occurrences(sym), nil
+	}
+	if def := ss.columnAliasAt(ident); def != nil {
+		return ident, ss.columnAliasOccurrences(def), nil
+	}
+	if col := ss.columnAt(ident); col != nil {
+		return ident, ss.columnOccurrences(col), nil
+	}
+	return nil, nil, nil
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -178,3 +179,142 @@ func TestRenameMain(t *testing.T) {
 		})
 	}
 }
+
+func TestRenameScopes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   lsp.Position
+		want  []string
+	}{
+		{
+			name:  "table alias and column of the same name",
+			input: "SELECT c.c FROM city c WHERE c = 1",
+			pos:   lsp.Position{Line: 0, Character: 7},
+			want:  []string{"0:7-0:8", "0:21-0:22"},
+		},
+		{
+			name:  "shadowing sub query alias",
+			input: "SELECT c.ID FROM city c WHERE c.ID IN (SELECT c.ID FROM country c)",
+			pos:   lsp.Position{Line: 0, Character: 64},
+			want:  []string{"0:46-0:47", "0:64-0:65"},
+		},
+		{
+			name:  "sub query alias",
+			input: "SELECT s.n FROM (SELECT Name AS n FROM city) s WHERE s.n = 'x'",
+			pos:   lsp.Position{Line: 0, Character: 45},
+			want:  []string{"0:7-0:8", "0:45-0:46", "0:53-0:54"},
+		},
+		{
+			name:  "column alias",
+			input: "SELECT Name AS n, n + 1 AS Name FROM city ORDER BY n",
+			pos:   lsp.Position{Line: 0, Character: 51},
+			want:  []string{"0:15-0:16", "0:51-0:52"},
+		},
+		{
+			name:  "column alias of a sub query",
+			input: "SELECT s.n FROM (SELECT Name AS n FROM city) s",
+			pos:   lsp.Position{Line: 0, Character: 9},
+			want:  []string{"0:9-0:10", "0:32-0:33"},
+		},
+		{
+			name:  "cte",
+			input: "WITH x AS (SELECT Name AS n FROM city) SELECT x.n FROM x",
+			pos:   lsp.Position{Line: 0, Character: 5},
+			want:  []string{"0:5-0:6", "0:46-0:47", "0:55-0:56"},
+		},
+		{
+			name:  "column alias of a cte",
+			input: "WITH x AS (SELECT Name AS n FROM city) SELECT x.n FROM x",
+			pos:   lsp.Position{Line: 0, Character: 48},
+			want:  []string{"0:26-0:27", "0:48-0:49"},
+		},
+		{
+			name:  "table",
+			input: "SELECT c.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 18},
+			want:  []string{"0:17-0:21"},
+		},
+		{
+			name:  "table without alias",
+			input: "SELECT city.ID FROM city WHERE city.ID = 1",
+			pos:   lsp.Position{Line: 0, Character: 21},
+			want:  []string{"0:7-0:11", "0:20-0:24", "0:31-0:35"},
+		},
+		{
+			name:  "column",
+			input: "SELECT c.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 10},
+			want:  []string{"0:9-0:11"},
+		},
+		{
+			name:  "unqualified column",
+			input: "SELECT Name FROM city WHERE Name='x'",
+			pos:   lsp.Position{Line: 0, Character: 8},
+			want:  []string{"0:7-0:11", "0:28-0:32"},
+		},
+		{
+			name:  "alias after a tab",
+			input: "SELECT\n\tc.ID FROM city c",
+			pos:   lsp.Position{Line: 1, Character: 1},
+			want:  []string{"1:1-1:2", "1:16-1:17"},
+		},
+		{
+			name:  "alias after a surrogate pair",
+			input: "SELECT '😀', c.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 28},
+			want:  []string{"0:13-0:14", "0:28-0:29"},
+		},
+		{
+			name:  "column of an undefined qualifier",
+			input: "SELECT x.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 10},
+			want:  nil,
+		},
+		{
+			name:  "keyword",
+			input: "SELECT c.ID FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 2},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prepared, err := prepareRename(tt.input, lsp.PrepareRenameParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+					Position:     tt.pos,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (prepared != nil) != (tt.want != nil) {
+				t.Errorf("unexpected prepareRename result %+v", prepared)
+			}
+
+			got, err := rename(tt.input, 3, lsp.RenameParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+				Position:     tt.pos,
+				NewName:      "z",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ranges []string
+			if got != nil {
+				change := got.DocumentChanges[0]
+				if change.TextDocument.Version != 3 {
+					t.Errorf("unexpected version %d", change.TextDocument.Version)
+				}
+				for _, edit := range change.Edits {
+					r := edit.Range
+					ranges = append(ranges, fmt.Sprintf("%d:%d-%d:%d", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character))
+				}
+			}
+			if diff := cmp.Diff(tt.want, ranges); diff != "" {
+				t.Errorf("unmatch rename edits (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+)
+
+// resultFormatter writes the results of the statements of an execution, one
+// after the other. A formatter is created for each execution, so it may keep
+// state between the results.
+type resultFormatter interface {
+	format(w io.Writer, res *QueryResult) error
+}
+
+// resultFormatters are the formatters selectable with "-output=<name>", for
+// the results of a database of driver.
+var resultFormatters = map[string]func(driver dialect.DatabaseDriver) resultFormatter{
+	"table":    func(dialect.DatabaseDriver) resultFormatter { return &tableFormatter{} },
+	"vertical": func(dialect.DatabaseDriver) resultFormatter { return &tableFormatter{vertical: true} },
+	"csv":      func(dialect.DatabaseDriver) resultFormatter { return &csvFormatter{comma: ','} },
+	"tsv":      func(dialect.DatabaseDriver) resultFormatter { return &csvFormatter{comma: '\t'} },
+	"markdown": func(dialect.DatabaseDriver) resultFormatter { return &markdownFormatter{} },
+	"jsonl":    func(dialect.DatabaseDriver) resultFormatter { return &jsonLinesFormatter{} },
+	"insert": func(driver dialect.DatabaseDriver) resultFormatter {
+		return &insertFormatter{quote: identifierQuote(driver)}
+	},
+}
+
+// isQueryResult reports whether res is the result of a query, as opposed to a
+// statement returning the number of affected rows.
+func isQueryResult(res *QueryResult) bool {
+	return res.RowsAffected == nil
+}
+
+func columnNames(res *QueryResult) []string {
+	names := make([]string, len(res.Columns))
+	for i, c := range res.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// cellString returns v, a value of a row of a QueryResult, as text. null is
+// returned for NULL.
+func cellString(v interface{}, null string) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return null, nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case map[string]interface{}, []interface{}:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+func rowStrings(row []interface{}, null string) ([]string, error) {
+	cells := make([]string, len(row))
+	for i, v := range row {
+		s, err := cellString(v, null)
+		if err != nil {
+			return nil, err
+		}
+		cells[i] = s
+	}
+	return cells, nil
+}
+
+// tableFormatter writes ASCII tables, or a record per row if vertical, and
+// the number of affected rows of the other statements.
+type tableFormatter struct {
+	vertical bool
+}
+
+func (f *tableFormatter) format(w io.Writer, res *QueryResult) error {
+	if !isQueryResult(res) {
+		fmt.Fprintf(w, "Query OK, %d row affected", *res.RowsAffected)
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "")
+		return nil
+	}
+
+	columns := columnNames(res)
+	if f.vertical {
+		table := newVerticalTableWriter(w)
+		table.setHeaders(columns)
+		for _, row := range res.Rows {
+			cells, err := rowStrings(row, "<nil>")
+			if err != nil {
+				return err
+			}
+			table.appendRow(cells)
+		}
+		table.render()
+	} else {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(columns)
+		for _, row := range res.Rows {
+			cells, err := rowStrings(row, "<nil>")
+			if err != nil {
+				return err
+			}
+			table.Append(cells)
+		}
+		table.Render()
+	}
+	fmt.Fprintf(w, "%d rows in set", len(res.Rows))
+	if res.Truncated {
+		if res.Cursor != "" {
+			fmt.Fprintf(w, ", truncated at the max rows (fetchNextPage %s for more)", res.Cursor)
+		} else {
+			fmt.Fprint(w, ", truncated at the max rows")
+		}
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "")
+	return nil
+}
+
+// csvFormatter writes the rows of queries as CSV, or TSV if comma is a tab.
+// The result sets of several queries are separated by an empty line.
+type csvFormatter struct {
+	comma rune
+	sets  int
+}
+
+func (f *csvFormatter) format(w io.Writer, res *QueryResult) error {
+	if !isQueryResult(res) {
+		return nil
+	}
+	if f.sets > 0 {
+		fmt.Fprintln(w, "")
+	}
+	f.sets++
+
+	cw := csv.NewWriter(w)
+	cw.Comma = f.comma
+	if err := cw.Write(columnNames(res)); err != nil {
+		return err
+	}
+	for _, row := range res.Rows {
+		cells, err := rowStrings(row, "")
+		if err != nil {
+			return err
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownFormatter writes the rows of queries as GitHub Flavored Markdown
+// tables.
+type markdownFormatter struct {
+	sets int
+}
+
+var markdownCellReplacer = strings.NewReplacer(
+	`|`, `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+func (f *markdownFormatter) format(w io.Writer, res *QueryResult) error {
+	if !isQueryResult(res) {
+		return nil
+	}
+	if f.sets > 0 {
+		fmt.Fprintln(w, "")
+	}
+	f.sets++
+
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			cells[i] = markdownCellReplacer.Replace(cell)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	writeRow(columnNames(res))
+	separators := make([]string, len(res.Columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	writeRow(separators)
+	for _, row := range res.Rows {
+		cells, err := rowStrings(row, "NULL")
+		if err != nil {
+			return err
+		}
+		writeRow(cells)
+	}
+	return nil
+}
+
+// jsonLinesFormatter writes a JSON object per row of the queries, keyed by
+// column name.
+type jsonLinesFormatter struct{}
+
+func (f *jsonLinesFormatter) format(w io.Writer, res *QueryResult) error {
+	if !isQueryResult(res) {
+		return nil
+	}
+
+	columns := columnNames(res)
+	for _, row := range res.Rows {
+		// Written by hand to keep the columns in order
+		var b strings.Builder
+		b.WriteString("{")
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, err := json.Marshal(columns[i])
+			if err != nil {
+				return err
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteString(":")
+			b.Write(val)
+		}
+		b.WriteString("}")
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertFormatter writes an INSERT statement per row of the queries. The
+// rows are inserted into the table the query selects from, or into "result"
+// if there is not exactly one. The names that are not plain words are quoted
+// with quote.
+type insertFormatter struct {
+	quote rune
+}
+
+func (f *insertFormatter) format(w io.Writer, res *QueryResult) error {
+	if !isQueryResult(res) {
+		return nil
+	}
+
+	table := f.tableName(res.Query)
+	names := columnNames(res)
+	for i, name := range names {
+		names[i] = quoteIdentifier(name, f.quote)
+	}
+	columns := strings.Join(names, ", ")
+	for _, row := range res.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			literal, err := sqlLiteral(v)
+			if err != nil {
+				return err
+			}
+			values[i] = literal
+		}
+		fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n", table, columns, strings.Join(values, ", "))
+	}
+	return nil
+}
+
+func (f *insertFormatter) tableName(query string) string {
+	name := "result"
+	if parsed, err := parser.Parse(query); err == nil {
+		refs := parseutil.ExtractTableRefs(parsed)
+		if len(refs) == 1 && refs[0].Ident != nil {
+			name = refs[0].Info.Name
+			if schema := refs[0].Info.DatabaseSchema; schema != "" {
+				return quoteIdentifier(schema, f.quote) + "." + quoteIdentifier(name, f.quote)
+			}
+		}
+	}
+	return quoteIdentifier(name, f.quote)
+}
+
+// identifierQuote returns the character quoting the identifiers of driver.
+func identifierQuote(driver dialect.DatabaseDriver) rune {
+	switch driver {
+	case dialect.DatabaseDriverMySQL, dialect.DatabaseDriverMySQL8, dialect.DatabaseDriverMySQL57, dialect.DatabaseDriverMySQL56:
+		return '`'
+	}
+	return '"'
+}
+
+// quoteIdentifier returns name as is if it is a plain word, and quoted with
+// quote otherwise, such as for keywords or names with spaces.
+func quoteIdentifier(name string, quote rune) string {
+	plain := name != "" && dialect.MatchKeyword(strings.ToUpper(name)) == dialect.Unmatched
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	q := string(quote)
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// sqlLiteral returns v, a value of a row of a QueryResult, as an SQL literal.
+func sqlLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case map[string]interface{}, []interface{}:
+		s, err := cellString(v, "")
+		if err != nil {
+			return "", err
+		}
+		return sqlLiteral(s)
+	default:
+		return cellString(v, "NULL")
+	}
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func testResults() []*QueryResult {
+	rowsAffected := int64(2)
+	return []*QueryResult{
+		{
+			Statement:    0,
+			Query:        "INSERT INTO city VALUES (1, 'Kabul', 1780000), (2, 'Qandahar', NULL);",
+			RowsAffected: &rowsAffected,
+		},
+		{
+			Statement: 1,
+			Query:     "SELECT id, name, population FROM world.city;",
+			Columns: []*database.Column{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "name", Type: "TEXT"},
+				{Name: "population", Type: "INTEGER"},
+			},
+			Rows: [][]interface{}{
+				{int64(1), "Kabul", int64(1780000)},
+				{int64(2), "Qandahar, \"Kandahar\" | 'Q'", nil},
+			},
+		},
+		{
+			Statement: 2,
+			Query:     "SELECT 1.5 AS ratio, true AS ok;",
+			Columns: []*database.Column{
+				{Name: "ratio", Type: ""},
+				{Name: "ok", Type: ""},
+			},
+			Rows: [][]interface{}{
+				{1.5, true},
+			},
+		},
+	}
+}
+
+func TestInsertFormatterQuoting(t *testing.T) {
+	res := &QueryResult{
+		Query: `SELECT COUNT(*), Name AS "a b", "Order" FROM "Group" GROUP BY Name`,
+		Columns: []*database.Column{
+			{Name: "count(*)"},
+			{Name: "a b"},
+			{Name: "Order"},
+			{Name: "x\"y"},
+		},
+		Rows: [][]interface{}{
+			{int64(1), "Kabul", int64(2), nil},
+		},
+	}
+	tests := []struct {
+		driver dialect.DatabaseDriver
+		want   string
+	}{
+		{
+			driver: dialect.DatabaseDriverPostgreSQL,
+			want:   "INSERT INTO \"Group\" (\"count(*)\", \"a b\", \"Order\", \"x\"\"y\") VALUES (1, 'Kabul', 2, NULL);\n",
+		},
+		{
+			driver: dialect.DatabaseDriverMySQL,
+			want:   "INSERT INTO `Group` (`count(*)`, `a b`, `Order`, `x\"y`) VALUES (1, 'Kabul', 2, NULL);\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.driver), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := resultFormatters["insert"](tt.driver).format(buf, res); err != nil {
+				t.Fatal("format:", err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("unmatch output (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResultFormatters(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{
+			output: "table",
+			want: "Query OK, 2 row affected\n\n\n" +
+				"+----+----------------------------+------------+\n" +
+				"| ID |            NAME            | POPULATION |\n" +
+				"+----+----------------------------+------------+\n" +
+				"|  1 | Kabul                      |    1780000 |\n" +
+				"|  2 | Qandahar, \"Kandahar\" | 'Q' | <nil>      |\n" +
+				"+----+----------------------------+------------+\n" +
+				"2 rows in set\n\n\n" +
+				"+-------+------+\n" +
+				"| RATIO |  OK  |\n" +
+				"+-------+------+\n" +
+				"|   1.5 | true |\n" +
+				"+-------+------+\n" +
+				"1 rows in set\n\n\n",
+		},
+		{
+			output: "csv",
+			want: "id,name,population\n" +
+				"1,Kabul,1780000\n" +
+				"2,\"Qandahar, \"\"Kandahar\"\" | 'Q'\",\n" +
+				"\n" +
+				"ratio,ok\n" +
+				"1.5,true\n",
+		},
+		{
+			output: "tsv",
+			want: "id\tname\tpopulation\n" +
+				"1\tKabul\t1780000\n" +
+				"2\t\"Qandahar, \"\"Kandahar\"\" | 'Q'\"\t\n" +
+				"\n" +
+				"ratio\tok\n" +
+				"1.5\ttrue\n",
+		},
+		{
+			output: "markdown",
+			want: "| id | name | population |\n" +
+				"| --- | --- | --- |\n" +
+				"| 1 | Kabul | 1780000 |\n" +
+				"| 2 | Qandahar, \"Kandahar\" \\| 'Q' | NULL |\n" +
+				"\n" +
+				"| ratio | ok |\n" +
+				"| --- | --- |\n" +
+				"| 1.5 | true |\n",
+		},
+		{
+			output: "jsonl",
+			want: `{"id":1,"name":"Kabul","population":1780000}` + "\n" +
+				`{"id":2,"name":"Qandahar, \"Kandahar\" | 'Q'","population":null}` + "\n" +
+				`{"ratio":1.5,"ok":true}` + "\n",
+		},
+		{
+			output: "insert",
+			want: "INSERT INTO world.city (id, name, population) VALUES (1, 'Kabul', 1780000);\n" +
+				"INSERT INTO world.city (id, name, population) VALUES (2, 'Qandahar, \"Kandahar\" | ''Q''', NULL);\n" +
+				"INSERT INTO \"result\" (ratio, ok) VALUES (1.5, TRUE);\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			formatter := resultFormatters[tt.output]("")
+			buf := new(bytes.Buffer)
+			for _, res := range testResults() {
+				if err := formatter.format(buf, res); err != nil {
+					t.Fatal("format:", err)
+				}
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("unmatch output (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_executeQueryOutputFile(t *testing.T) {
+	root := t.TempDir()
+	tx := newTestContext()
+	tx.rootURI = "file://" + filepath.ToSlash(root)
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         "sqlite3",
+				DataSourceName: "file:execute_query_output_file?mode=memory&cache=shared",
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT 1 AS id, 'Kabul' AS name UNION ALL SELECT 2, 'Qandahar';")
+
+	execute := func(args ...interface{}) (string, error) {
+		executeCommandParams := lsp.ExecuteCommandParams{
+			Command:   CommandExecuteQuery,
+			Arguments: append([]interface{}{testFileURI, "-output=csv"}, args...),
+		}
+		var got string
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", executeCommandParams, &got)
+		return got, err
+	}
+
+	// Relative paths are in the workspace root
+	got, err := execute("-output-file=city.csv")
+	if err != nil {
+		t.Fatal("conn.Call workspace/executeCommand:", err)
+	}
+	path := filepath.Join(root, "city.csv")
+	if want := "2 rows written to " + path; got != want {
+		t.Errorf("unmatch message, want %q, got %q", want, got)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("id,name\n1,Kabul\n2,Qandahar\n", string(b)); diff != "" {
+		t.Errorf("unmatch file (- want, + got):\n%s", diff)
+	}
+
+	// Existing files are only replaced with -overwrite
+	if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute("-output-file=" + path); err == nil {
+		t.Error("expected error for the existing file")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "keep" {
+		t.Errorf("the existing file was replaced: %q", b)
+	}
+	if _, err := execute("-output-file="+path, "-overwrite"); err != nil {
+		t.Fatal("conn.Call workspace/executeCommand:", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) == "keep" {
+		t.Error("the existing file was not replaced with -overwrite")
+	}
+
+	// Paths out of the workspace root are refused
+	outside := filepath.Join(t.TempDir(), "city.csv")
+	for _, path := range []string{"../city.csv", outside} {
+		if _, err := execute("-output-file=" + path); err == nil {
+			t.Errorf("expected error for the path out of the root, %s", path)
+		}
+	}
+	if _, err := os.Stat(outside); err == nil {
+		t.Errorf("the file out of the root was written, %s", outside)
+	}
+}
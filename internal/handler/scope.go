@@ -0,0 +1,597 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+type sqlSymbolKind int
+
+const (
+	aliasSymbolKind sqlSymbolKind = iota
+	cteSymbolKind
+	tableSymbolKind
+)
+
+// sqlSymbol is a named object that identifiers in a statement refer to.
+type sqlSymbol struct {
+	kind sqlSymbolKind
+	// def is the defining identifier of an alias or a CTE. Table symbols
+	// are identified by name instead, as they can be defined outside of the
+	// document.
+	def    *ast.Identifier
+	schema string
+	name   string
+}
+
+func (s *sqlSymbol) equal(other *sqlSymbol) bool {
+	if s.kind != other.kind {
+		return false
+	}
+	if s.kind != tableSymbolKind {
+		return s.def == other.def
+	}
+	if s.schema != "" && other.schema != "" && !strings.EqualFold(s.schema, other.schema) {
+		return false
+	}
+	return strings.EqualFold(s.name, other.name)
+}
+
+// occurrence is an identifier referring to a symbol.
+type occurrence struct {
+	ident *ast.Identifier
+	// isDefinition is true for the identifier declaring the symbol, such as
+	// an alias, a CTE name or the name in CREATE TABLE.
+	isDefinition bool
+	// isWrite is true when the statement modifies the referred table.
+	isWrite bool
+}
+
+// scope is the statement or a sub query. Table references of a scope are
+// visible in the scope and in the sub queries nested in it.
+type scope struct {
+	node   ast.Node
+	parent *scope
+	refs   []*parseutil.TableRef
+}
+
+// statementScopes resolves the identifiers of a statement to symbols.
+type statementScopes struct {
+	stmt   *ast.Statement
+	ctes   []*parseutil.CTEInfo
+	create *parseutil.CreateInfo
+	scopes []*scope
+	// members maps qualifiers to their member identifiers
+	members map[*ast.Identifier]*ast.MemberIdentifier
+	// qualified maps qualified column names to their member identifiers
+	qualified map[*ast.Identifier]*ast.MemberIdentifier
+	functions map[*ast.Identifier]bool
+	verb      string
+	// target is the identifier of the main table of the statement
+	target *ast.Identifier
+	// written are the columns assigned by the statement
+	written map[*ast.Identifier]bool
+	// aliases are the column aliases, indexed on first use
+	aliases *columnAliasIndex
+}
+
+func newStatementScopes(stmt *ast.Statement) *statementScopes {
+	ss := &statementScopes{
+		stmt:      stmt,
+		ctes:      parseutil.ExtractCTEs(stmt),
+		create:    parseutil.ExtractCreate(stmt),
+		members:   map[*ast.Identifier]*ast.MemberIdentifier{},
+		qualified: map[*ast.Identifier]*ast.MemberIdentifier{},
+		functions: map[*ast.Identifier]bool{},
+	}
+	if nodes := statementNodes(stmt); len(nodes) > 0 {
+		ss.verb = strings.ToUpper(strings.Fields(statementVerb(nodes, ss.ctes).String())[0])
+	}
+	if ref := mainTableRef(stmt, ss.ctes); ref != nil {
+		ss.target = ref.Ident
+	}
+
+	// Scopes are sorted from outer to inner as FindRecursive walks depth first
+	ss.scopes = append(ss.scopes, &scope{node: stmt})
+	subQueryMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeParenthesis}}
+	for _, node := range astutil.NewNodeReader(stmt).FindRecursive(subQueryMatcher) {
+		if parseutil.IsSubQuery(node) {
+			sc := &scope{node: node}
+			sc.parent = ss.innermostScope(node)
+			ss.scopes = append(ss.scopes, sc)
+		}
+	}
+	for _, ref := range parseutil.ExtractTableRefs(stmt) {
+		sc := ss.innermostScope(ref.Node)
+		sc.refs = append(sc.refs, ref)
+	}
+
+	memberMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeMemberIdentifier}}
+	for _, node := range astutil.NewNodeReader(stmt).FindRecursive(memberMatcher) {
+		mi, ok := node.(*ast.MemberIdentifier)
+		if !ok {
+			continue
+		}
+		if mi.ParentIdent != nil {
+			ss.members[mi.ParentIdent] = mi
+		}
+		if mi.ChildIdent != nil {
+			ss.qualified[mi.ChildIdent] = mi
+		}
+	}
+
+	functionMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeFunctionLiteral}}
+	for _, node := range astutil.NewNodeReader(stmt).FindRecursive(functionMatcher) {
+		if ident, ok := node.(*ast.FunctionLiteral).GetTokens()[0].(*ast.Identifier); ok {
+			ss.functions[ident] = true
+		}
+	}
+	ss.written = ss.writtenColumns()
+	return ss
+}
+
+// innermostScope returns the innermost scope strictly enclosing node.
+func (ss *statementScopes) innermostScope(node ast.Node) *scope {
+	res := ss.scopes[0]
+	for _, sc := range ss.scopes[1:] {
+		if sc.node == node {
+			continue
+		}
+		if astutil.IsEnclose(sc.node, node.Pos()) && astutil.IsEnclose(sc.node, node.End()) {
+			res = sc
+		}
+	}
+	return res
+}
+
+func (ss *statementScopes) cte(name string) *parseutil.CTEInfo {
+	for _, cte := range ss.ctes {
+		if strings.EqualFold(cte.Name, name) {
+			return cte
+		}
+	}
+	return nil
+}
+
+// resolveQualifier finds the table reference that a column qualifier in sc
+// refers to. Aliases shadow table names, and inner scopes shadow outer ones.
+func (ss *statementScopes) resolveQualifier(name string, sc *scope) *parseutil.TableRef {
+	for ; sc != nil; sc = sc.parent {
+		for _, ref := range sc.refs {
+			if ref.AliasIdent != nil && strings.EqualFold(ref.Info.Alias, name) {
+				return ref
+			}
+		}
+		for _, ref := range sc.refs {
+			if ref.Ident != nil && ref.AliasIdent == nil && strings.EqualFold(ref.Info.Name, name) {
+				return ref
+			}
+		}
+	}
+	return nil
+}
+
+// refSymbol returns the symbol named by the table name of a reference.
+func (ss *statementScopes) refSymbol(ref *parseutil.TableRef) *sqlSymbol {
+	if ref.Info.DatabaseSchema == "" {
+		if cte := ss.cte(ref.Info.Name); cte != nil {
+			return &sqlSymbol{kind: cteSymbolKind, def: cte.Ident, name: cte.Name}
+		}
+	}
+	return &sqlSymbol{kind: tableSymbolKind, schema: ref.Info.DatabaseSchema, name: ref.Info.Name}
+}
+
+// symbolAt returns the symbol that ident refers to, or nil if ident is not
+// an alias, a CTE or a table.
+func (ss *statementScopes) symbolAt(ident *ast.Identifier) *sqlSymbol {
+	for _, cte := range ss.ctes {
+		if cte.Ident == ident {
+			return &sqlSymbol{kind: cteSymbolKind, def: cte.Ident, name: cte.Name}
+		}
+	}
+	if ss.isCreateTable() && ss.create.Ident == ident {
+		return &sqlSymbol{kind: tableSymbolKind, schema: ss.create.DatabaseSchema, name: ss.create.Name}
+	}
+	for _, sc := range ss.scopes {
+		for _, ref := range sc.refs {
+			if ref.AliasIdent == ident {
+				return &sqlSymbol{kind: aliasSymbolKind, def: ref.AliasIdent, name: ref.Info.Alias}
+			}
+			if ref.Ident == ident {
+				return ss.refSymbol(ref)
+			}
+		}
+	}
+	if mi, ok := ss.members[ident]; ok {
+		ref := ss.resolveQualifier(ident.NoQuoteString(), ss.innermostScope(mi))
+		if ref == nil {
+			return nil
+		}
+		if ref.AliasIdent != nil {
+			return &sqlSymbol{kind: aliasSymbolKind, def: ref.AliasIdent, name: ref.Info.Alias}
+		}
+		return ss.refSymbol(ref)
+	}
+	return nil
+}
+
+// occurrences returns the identifiers of the statement referring to sym.
+func (ss *statementScopes) occurrences(sym *sqlSymbol) []*occurrence {
+	type candidate struct {
+		ident        *ast.Identifier
+		isDefinition bool
+	}
+	candidates := []candidate{}
+	for _, cte := range ss.ctes {
+		candidates = append(candidates, candidate{ident: cte.Ident, isDefinition: true})
+	}
+	if ss.isCreateTable() {
+		candidates = append(candidates, candidate{ident: ss.create.Ident, isDefinition: true})
+	}
+	for _, sc := range ss.scopes {
+		for _, ref := range sc.refs {
+			if ref.AliasIdent != nil {
+				candidates = append(candidates, candidate{ident: ref.AliasIdent, isDefinition: true})
+			}
+			if ref.Ident != nil {
+				candidates = append(candidates, candidate{ident: ref.Ident})
+			}
+		}
+	}
+	for ident := range ss.members {
+		candidates = append(candidates, candidate{ident: ident})
+	}
+
+	occs := []*occurrence{}
+	for _, c := range candidates {
+		found := ss.symbolAt(c.ident)
+		if found == nil || !found.equal(sym) {
+			continue
+		}
+		occs = append(occs, &occurrence{
+			ident:        c.ident,
+			isDefinition: c.isDefinition,
+			isWrite:      c.isDefinition || (c.ident == ss.target && ss.isWriteStatement()),
+		})
+	}
+	sort.Slice(occs, func(i, j int) bool {
+		return token.ComparePos(occs[i].ident.Pos(), occs[j].ident.Pos()) < 0
+	})
+	return occs
+}
+
+// column is a column that an identifier refers to. ref is the table of the
+// column, and nil for an unqualified column that may belong to any of the
+// tables of its nearest scope, its candidates.
+type column struct {
+	name       string
+	ref        *parseutil.TableRef
+	candidates []*parseutil.TableRef
+}
+
+// tables returns the tables that c may belong to, or nil if they are unknown.
+func (c *column) tables() []*parseutil.TableRef {
+	if c.ref != nil {
+		return []*parseutil.TableRef{c.ref}
+	}
+	return c.candidates
+}
+
+func (c *column) matches(other *column) bool {
+	if !strings.EqualFold(c.name, other.name) {
+		return false
+	}
+	tables, otherTables := c.tables(), other.tables()
+	if len(tables) == 0 || len(otherTables) == 0 {
+		return true
+	}
+	for _, ref := range tables {
+		for _, otherRef := range otherTables {
+			if ref == otherRef {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnAt returns the column that ident refers to, or nil if ident is a
+// table, an alias, a qualifier or a function name.
+func (ss *statementScopes) columnAt(ident *ast.Identifier) *column {
+	if ss.functions[ident] || ss.members[ident] != nil || ss.symbolAt(ident) != nil {
+		return nil
+	}
+	if ss.create != nil && ss.create.Ident == ident {
+		return nil
+	}
+	col := &column{name: ident.NoQuoteString()}
+	if mi, ok := ss.qualified[ident]; ok {
+		col.ref = ss.resolveQualifier(mi.ParentIdent.NoQuoteString(), ss.innermostScope(mi))
+		if col.ref == nil {
+			return nil
+		}
+		return col
+	}
+
+	// The column list of INSERT names the columns of its target, which is
+	// not visible to its SELECT
+	isInsert := ss.verb == "INSERT" || ss.verb == "REPLACE"
+	for sc := ss.innermostScope(ident); sc != nil; sc = sc.parent {
+		refs := []*parseutil.TableRef{}
+		for _, ref := range sc.refs {
+			isTarget := ref.Ident != nil && ref.Ident == ss.target
+			if isInsert && isTarget && ss.written[ident] {
+				col.ref = ref
+				return col
+			}
+			if !isInsert || !isTarget {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		if len(refs) == 1 {
+			col.ref = refs[0]
+		} else {
+			col.candidates = refs
+		}
+		break
+	}
+	return col
+}
+
+// columnOccurrences returns the identifiers of the statement referring to col.
+func (ss *statementScopes) columnOccurrences(col *column) []*occurrence {
+	occs := []*occurrence{}
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+	for _, node := range astutil.NewNodeReader(ss.stmt).FindRecursive(matcher) {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			continue
+		}
+		if found := ss.columnAt(ident); found != nil && found.matches(col) {
+			occs = append(occs, &occurrence{
+				ident:   ident,
+				isWrite: ss.written[ident],
+			})
+		}
+	}
+	sort.Slice(occs, func(i, j int) bool {
+		return token.ComparePos(occs[i].ident.Pos(), occs[j].ident.Pos()) < 0
+	})
+	return occs
+}
+
+// writtenColumns returns the columns assigned by the statement, that is the
+// column list of INSERT and the SET targets of UPDATE. They are computed once
+// as written.
+func (ss *statementScopes) writtenColumns() map[*ast.Identifier]bool {
+	written := map[*ast.Identifier]bool{}
+	nodes := statementNodes(ss.stmt)
+	switch ss.verb {
+	case "INSERT", "REPLACE":
+		if ss.target == nil {
+			break
+		}
+		for i, node := range nodes {
+			if !astutil.IsEnclose(node, ss.target.Pos()) {
+				continue
+			}
+			var columns ast.Node
+			if fl, ok := node.(*ast.FunctionLiteral); ok {
+				columns = fl.GetTokens()[len(fl.GetTokens())-1]
+			} else if i+1 < len(nodes) {
+				columns = nodes[i+1]
+			}
+			if parenthesis, ok := columns.(*ast.Parenthesis); ok && !parseutil.IsSubQuery(parenthesis) {
+				matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+				for _, ident := range astutil.NewNodeReader(parenthesis).FindRecursive(matcher) {
+					written[ident.(*ast.Identifier)] = true
+				}
+			}
+			break
+		}
+	case "UPDATE":
+		setMatcher := astutil.NodeMatcher{ExpectKeyword: []string{"SET"}}
+		for i, node := range nodes {
+			if !setMatcher.IsMatch(node) || i+1 == len(nodes) {
+				continue
+			}
+			assignments := []ast.Node{nodes[i+1]}
+			if list, ok := nodes[i+1].(*ast.IdentifierList); ok {
+				assignments = list.GetIdentifiers()
+			}
+			for _, assignment := range assignments {
+				comparison, ok := assignment.(*ast.Comparison)
+				if !ok {
+					continue
+				}
+				switch left := comparison.GetLeft().(type) {
+				case *ast.Identifier:
+					written[left] = true
+				case *ast.MemberIdentifier:
+					if left.ChildIdent != nil {
+						written[left.ChildIdent] = true
+					}
+				}
+			}
+		}
+	}
+	return written
+}
+
+// columnAliasIndex indexes the column aliases of a statement, the alias
+// identifiers of its expressions such as n in SELECT Name AS n.
+type columnAliasIndex struct {
+	// byName maps the upper case names to the aliases, in source order
+	byName map[string][]*ast.Identifier
+	// scopes maps the aliases to the scopes defining them
+	scopes map[*ast.Identifier]*scope
+	// exprs are the identifiers of the aliased expressions, which are not
+	// uses of the aliases of their scope
+	exprs map[*ast.Identifier]bool
+}
+
+// columnAliases returns the index of the column aliases of the statement,
+// built on first use.
+func (ss *statementScopes) columnAliases() *columnAliasIndex {
+	if ss.aliases != nil {
+		return ss.aliases
+	}
+	refAliases := map[*ast.Identifier]bool{}
+	for _, sc := range ss.scopes {
+		for _, ref := range sc.refs {
+			if ref.AliasIdent != nil {
+				refAliases[ref.AliasIdent] = true
+			}
+		}
+	}
+	idx := &columnAliasIndex{
+		byName: map[string][]*ast.Identifier{},
+		scopes: map[*ast.Identifier]*scope{},
+		exprs:  map[*ast.Identifier]bool{},
+	}
+	identMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeAliased}}
+	for _, node := range astutil.NewNodeReader(ss.stmt).FindRecursive(matcher) {
+		aliased := node.(*ast.Aliased)
+		def, ok := aliased.AliasedName.(*ast.Identifier)
+		if !ok || refAliases[def] {
+			continue
+		}
+		name := strings.ToUpper(def.NoQuoteString())
+		idx.byName[name] = append(idx.byName[name], def)
+		sc := ss.innermostScope(aliased)
+		idx.scopes[def] = sc
+
+		exprs := []ast.Node{aliased.RealName}
+		if list, ok := aliased.RealName.(ast.TokenList); ok {
+			exprs = append(exprs, astutil.NewNodeReader(list).FindRecursive(identMatcher)...)
+		}
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Identifier); ok && ss.innermostScope(ident) == sc {
+				idx.exprs[ident] = true
+			}
+		}
+	}
+	ss.aliases = idx
+	return idx
+}
+
+// columnAliasAt returns the column alias that ident defines or refers to, or
+// nil. An alias is referred to without a qualifier in the scope defining
+// it, and with the alias of its sub query or the name of its CTE outside.
+func (ss *statementScopes) columnAliasAt(ident *ast.Identifier) *ast.Identifier {
+	idx := ss.columnAliases()
+	if _, ok := idx.scopes[ident]; ok {
+		return ident
+	}
+	defs := idx.byName[strings.ToUpper(ident.NoQuoteString())]
+	if len(defs) == 0 {
+		return nil
+	}
+
+	if mi, ok := ss.qualified[ident]; ok {
+		ref := ss.resolveQualifier(mi.ParentIdent.NoQuoteString(), ss.innermostScope(mi))
+		if ref == nil {
+			return nil
+		}
+		var body ast.Node
+		if ref.Ident == nil {
+			body = ref.Node
+		} else if sym := ss.refSymbol(ref); sym.kind == cteSymbolKind {
+			if cte := ss.cte(sym.name); cte != nil && cte.Query != nil {
+				body = cte.Query
+			}
+		}
+		if body == nil {
+			return nil
+		}
+		for _, def := range defs {
+			if astutil.IsEnclose(body, def.Pos()) {
+				return def
+			}
+		}
+		return nil
+	}
+
+	if idx.exprs[ident] || ss.functions[ident] || ss.members[ident] != nil || ss.symbolAt(ident) != nil {
+		return nil
+	}
+	sc := ss.innermostScope(ident)
+	for _, def := range defs {
+		if idx.scopes[def] == sc {
+			return def
+		}
+	}
+	return nil
+}
+
+// columnAliasOccurrences returns the identifiers of the statement referring
+// to the column alias def.
+func (ss *statementScopes) columnAliasOccurrences(def *ast.Identifier) []*occurrence {
+	occs := []*occurrence{}
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+	for _, node := range astutil.NewNodeReader(ss.stmt).FindRecursive(matcher) {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || ss.columnAliasAt(ident) != def {
+			continue
+		}
+		occs = append(occs, &occurrence{
+			ident:        ident,
+			isDefinition: ident == def,
+			isWrite:      ident == def,
+		})
+	}
+	sort.Slice(occs, func(i, j int) bool {
+		return token.ComparePos(occs[i].ident.Pos(), occs[j].ident.Pos()) < 0
+	})
+	return occs
+}
+
+func (ss *statementScopes) isCreateTable() bool {
+	return ss.create != nil && (ss.create.Kind == "TABLE" || ss.create.Kind == "VIEW")
+}
+
+// isWriteStatement reports whether the main table of the statement is
+// modified.
+func (ss *statementScopes) isWriteStatement() bool {
+	switch ss.verb {
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "TRUNCATE":
+		return true
+	}
+	return false
+}
+
+// identifierAt returns the identifier at pos. An identifier starting at pos
+// is preferred over one ending at pos.
+func identifierAt(list ast.TokenList, pos token.Pos) *ast.Identifier {
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeIdentifier}}
+	var res *ast.Identifier
+	for _, node := range astutil.NewNodeReader(list).FindRecursive(matcher) {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || !astutil.IsEnclose(ident, pos) {
+			continue
+		}
+		if res == nil || token.ComparePos(pos, ident.End()) < 0 {
+			res = ident
+		}
+	}
+	return res
+}
+
+// statementAt returns the statement enclosing pos.
+func statementAt(stmts []*ast.Statement, pos token.Pos) *ast.Statement {
+	for _, stmt := range stmts {
+		if astutil.IsEnclose(stmt, pos) {
+			return stmt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+// clauseMatcher matches the keywords starting a clause of a statement or a
+// sub query.
+var clauseMatcher = astutil.NodeMatcher{
+	ExpectKeyword: []string{
+		"WITH",
+		"SELECT",
+		"INSERT INTO",
+		"UPDATE",
+		"DELETE FROM",
+		"SET",
+		"VALUES",
+		"FROM",
+		"JOIN",
+		"INNER JOIN",
+		"CROSS JOIN",
+		"OUTER JOIN",
+		"LEFT JOIN",
+		"RIGHT JOIN",
+		"LEFT OUTER JOIN",
+		"RIGHT OUTER JOIN",
+		"FULL JOIN",
+		"FULL OUTER JOIN",
+		"ON",
+		"USING",
+		"WHERE",
+		"GROUP BY",
+		"HAVING",
+		"ORDER BY",
+		"LIMIT",
+		"OFFSET",
+		"RETURNING",
+		"UNION",
+		"UNION ALL",
+		"EXCEPT",
+		"INTERSECT",
+	},
+}
+
+func (s *Server) handleTextDocumentSelectionRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SelectionRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return selectionRanges(f.Text, params.Positions)
+}
+
+// selectionRanges returns a selection range for each position, expanding
+// from the node at the position through its enclosing nodes, clauses and
+// parentheses up to the statement.
+func selectionRanges(text string, positions []lsp.Position) ([]lsp.SelectionRange, error) {
+	stmts, err := getStatements(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTextLines(text)
+	res := []lsp.SelectionRange{}
+	for _, position := range positions {
+		pos := lines.tokenPos(position)
+		ranges := []lsp.Range{}
+		if stmt := statementAt(stmts, pos); stmt != nil {
+			ranges = statementSelectionRanges(lines, stmt, pos)
+		}
+
+		sr := &lsp.SelectionRange{Range: lsp.Range{Start: position, End: position}}
+		if len(ranges) > 0 {
+			sr = nil
+			for i := len(ranges) - 1; i >= 0; i-- {
+				sr = &lsp.SelectionRange{Range: ranges[i], Parent: sr}
+			}
+		}
+		res = append(res, *sr)
+	}
+	return res, nil
+}
+
+// statementSelectionRanges returns the ranges enclosing pos in stmt, the
+// innermost first, in the UTF-16 columns of lines.
+func statementSelectionRanges(lines textLines, stmt *ast.Statement, pos token.Pos) []lsp.Range {
+	ranges := []lsp.Range{}
+	add := func(from, to token.Pos) {
+		if token.ComparePos(from, pos) > 0 || token.ComparePos(pos, to) > 0 {
+			return
+		}
+		rng := lines.posRange(from, to)
+		// Each range must strictly contain the previous one
+		if len(ranges) > 0 {
+			last := ranges[len(ranges)-1]
+			if rng == last || !rangeContains(rng, last) {
+				return
+			}
+		}
+		ranges = append(ranges, rng)
+	}
+
+	paths := selectionPaths(stmt, pos)
+	for i := len(paths) - 1; i >= 0; i-- {
+		reader := paths[i]
+		if !isBlankNode(reader.CurNode) {
+			add(reader.CurNode.Pos(), reader.CurNode.End())
+		}
+
+		nodes := reader.Node.GetTokens()
+		switch list := reader.Node.(type) {
+		case *ast.Statement:
+			if from, to, ok := clauseRange(nodes, reader.Index-1); ok {
+				add(from, to)
+			}
+		case *ast.Parenthesis:
+			if parseutil.IsSubQuery(list) {
+				if from, to, ok := clauseRange(nodes, reader.Index-1); ok {
+					add(from, to)
+				}
+			}
+			// The contents without the parentheses
+			inner := []ast.Node{}
+			for _, node := range nodes {
+				if !isBlankNode(node) {
+					inner = append(inner, node)
+				}
+			}
+			if len(inner) > 0 {
+				add(inner[0].Pos(), inner[len(inner)-1].End())
+			}
+		}
+	}
+
+	if nodes := statementNodes(stmt); len(nodes) > 0 {
+		add(nodes[0].Pos(), stmt.End())
+	}
+	return ranges
+}
+
+// selectionPaths returns the node walker paths to pos. The walker picks the
+// first node enclosing pos, so at the start of a word it stops at the
+// whitespace before, and the word is preferred instead.
+func selectionPaths(stmt *ast.Statement, pos token.Pos) []*astutil.NodeReader {
+	paths := parseutil.NewNodeWalker(stmt, pos).Paths
+	if len(paths) == 0 {
+		return paths
+	}
+	leaf := paths[len(paths)-1].CurNode
+	if !isBlankNode(leaf) || leaf.End() != pos {
+		return paths
+	}
+	next := parseutil.NewNodeWalker(stmt, token.Pos{Line: pos.Line, Col: pos.Col + 1}).Paths
+	if len(next) > 0 && next[len(next)-1].CurNode.Pos() == pos {
+		return next
+	}
+	return paths
+}
+
+// clauseRange returns the range of the clause containing nodes[idx], from
+// its keyword to the last node before the next clause.
+func clauseRange(nodes []ast.Node, idx int) (token.Pos, token.Pos, bool) {
+	start := -1
+	for i := idx; i >= 0; i-- {
+		if clauseMatcher.IsMatch(nodes[i]) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return token.Pos{}, token.Pos{}, false
+	}
+	end := start
+	for i := start + 1; i < len(nodes); i++ {
+		if clauseMatcher.IsMatch(nodes[i]) {
+			break
+		}
+		if !isBlankNode(nodes[i]) {
+			end = i
+		}
+	}
+	return nodes[start].Pos(), nodes[end].End(), true
+}
+
+// isBlankNode reports whether node is whitespace, a comment or punctuation
+// that delimits a list rather than belonging to it.
+func isBlankNode(node ast.Node) bool {
+	tok, ok := node.(ast.Token)
+	if !ok {
+		return false
+	}
+	switch tok.GetToken().Kind {
+	case token.Whitespace, token.Comment, token.MultilineComment, token.Semicolon, token.LParen, token.RParen:
+		return true
+	}
+	return false
+}
+
+func rangeContains(outer, inner lsp.Range) bool {
+	outerStart := token.Pos{Line: outer.Start.Line, Col: outer.Start.Character}
+	outerEnd := token.Pos{Line: outer.End.Line, Col: outer.End.Character}
+	innerStart := token.Pos{Line: inner.Start.Line, Col: inner.Start.Character}
+	innerEnd := token.Pos{Line: inner.End.Line, Col: inner.End.Character}
+	return token.ComparePos(outerStart, innerStart) <= 0 && token.ComparePos(innerEnd, outerEnd) <= 0
+}
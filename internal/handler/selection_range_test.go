@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestSelectionRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   lsp.Position
+		want  []string
+	}{
+		{
+			name:  "aliased member identifier",
+			input: "SELECT c.ID, c.Name AS n FROM city c",
+			pos:   lsp.Position{Line: 0, Character: 16},
+			want: []string{
+				"Name",
+				"c.Name",
+				"c.Name AS n",
+				"c.ID, c.Name AS n",
+				"SELECT c.ID, c.Name AS n",
+				"SELECT c.ID, c.Name AS n FROM city c",
+			},
+		},
+		{
+			name:  "start of identifier",
+			input: "SELECT ID FROM city",
+			pos:   lsp.Position{Line: 0, Character: 15},
+			want: []string{
+				"city",
+				"FROM city",
+				"SELECT ID FROM city",
+			},
+		},
+		{
+			name:  "sub query",
+			input: "SELECT * FROM city WHERE ID IN (SELECT ID FROM city WHERE x = 1);",
+			pos:   lsp.Position{Line: 0, Character: 58},
+			want: []string{
+				"x",
+				"x = 1",
+				"WHERE x = 1",
+				"SELECT ID FROM city WHERE x = 1",
+				"(SELECT ID FROM city WHERE x = 1)",
+				"WHERE ID IN (SELECT ID FROM city WHERE x = 1)",
+				"SELECT * FROM city WHERE ID IN (SELECT ID FROM city WHERE x = 1);",
+			},
+		},
+		{
+			name:  "second statement",
+			input: "SELECT 1;\n  SELECT 2",
+			pos:   lsp.Position{Line: 1, Character: 9},
+			want: []string{
+				"2",
+				"SELECT 2",
+			},
+		},
+		{
+			name:  "tab indent",
+			input: "SELECT\n\tc.ID,\n\tc.Name\nFROM city c",
+			pos:   lsp.Position{Line: 2, Character: 4},
+			want: []string{
+				"Name",
+				"c.Name",
+				"c.ID,\n\tc.Name",
+				"SELECT\n\tc.ID,\n\tc.Name",
+				"SELECT\n\tc.ID,\n\tc.Name\nFROM city c",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectionRanges(tt.input, []lsp.Position{tt.pos})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("unexpected number of selection ranges %d", len(got))
+			}
+			texts := []string{}
+			for sr := &got[0]; sr != nil; sr = sr.Parent {
+				texts = append(texts, rangeText(tt.input, sr.Range))
+			}
+			if diff := cmp.Diff(tt.want, texts); diff != "" {
+				t.Errorf("unmatch selection ranges (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func rangeText(text string, rng lsp.Range) string {
+	lines := splitLines(text)
+	if rng.Start.Line == rng.End.Line {
+		return string([]rune(lines[rng.Start.Line])[rng.Start.Character:rng.End.Character])
+	}
+	res := string([]rune(lines[rng.Start.Line])[rng.Start.Character:])
+	for _, line := range lines[rng.Start.Line+1 : rng.End.Line] {
+		res += "\n" + line
+	}
+	return res + "\n" + string([]rune(lines[rng.End.Line])[:rng.End.Character])
+}
@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+type semanticTokenType int
+
+// The order of the token types is the order of semanticTokenLegend.TokenTypes
+const (
+	stKeyword semanticTokenType = iota
+	stFunction
+	stNamespace
+	stTable
+	stColumn
+	stAlias
+	stCTE
+	stPlaceholder
+	stUnknown
+)
+
+const stmDeclaration = 1 << 0
+
+// semanticTokenLegend maps tables, columns and the other SQL objects to the
+// standard token types. Identifiers that do not exist in the database use
+// the custom "unknown" type.
+var semanticTokenLegend = lsp.SemanticTokensLegend{
+	TokenTypes: []string{
+		"keyword",
+		"function",
+		"namespace",
+		"class",
+		"property",
+		"variable",
+		"struct",
+		"parameter",
+		"unknown",
+	},
+	TokenModifiers: []string{
+		"declaration",
+	},
+}
+
+func (s *Server) handleTextDocumentSemanticTokensFull(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SemanticTokensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return semanticTokens(f.Text, nil, s.worker.Cache())
+}
+
+func (s *Server) handleTextDocumentSemanticTokensRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SemanticTokensRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return semanticTokens(f.Text, &params.Range, s.worker.Cache())
+}
+
+type semanticToken struct {
+	pos       token.Pos
+	end       token.Pos
+	tokenType semanticTokenType
+	modifiers int
+}
+
+// semanticTokens classifies the tokens of text starting in rng, or of the
+// whole text if rng is nil. Without a database cache, tables and columns are
+// assumed to exist.
+func semanticTokens(text string, rng *lsp.Range, dbCache *database.DBCache) (*lsp.SemanticTokens, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects created in the document are valid in every statement
+	created := map[string]bool{}
+	for _, node := range parsed.GetTokens() {
+		if stmt, ok := node.(*ast.Statement); ok {
+			if info := parseutil.ExtractCreate(stmt); info != nil {
+				created[strings.ToUpper(info.Name)] = true
+			}
+		}
+	}
+
+	toks := []*semanticToken{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		c := &semanticClassifier{
+			ss:      newStatementScopes(stmt),
+			dbCache: dbCache,
+			created: created,
+		}
+		toks = append(toks, c.classify()...)
+	}
+
+	lines := newTextLines(text)
+	data := []int{}
+	prev := lsp.Position{}
+	for _, tok := range toks {
+		// Tokens spanning several lines, such as quoted identifiers, are
+		// encoded a line at a time
+		for _, tokRange := range lines.lineRanges(tok.pos, tok.end) {
+			start := tokRange.Start
+			if rng != nil && !inRange(token.Pos{Line: start.Line, Col: start.Character}, *rng) {
+				continue
+			}
+			deltaCol := start.Character
+			if start.Line == prev.Line {
+				deltaCol -= prev.Character
+			}
+			length := tokRange.End.Character - start.Character
+			data = append(data, start.Line-prev.Line, deltaCol, length, int(tok.tokenType), tok.modifiers)
+			prev = start
+		}
+	}
+	return &lsp.SemanticTokens{Data: data}, nil
+}
+
+func inRange(pos token.Pos, rng lsp.Range) bool {
+	start := token.Pos{Line: rng.Start.Line, Col: rng.Start.Character}
+	end := token.Pos{Line: rng.End.Line, Col: rng.End.Character}
+	return token.ComparePos(start, pos) <= 0 && token.ComparePos(pos, end) < 0
+}
+
+type semanticClassifier struct {
+	ss      *statementScopes
+	dbCache *database.DBCache
+	created map[string]bool
+}
+
+func (c *semanticClassifier) classify() []*semanticToken {
+	functions := map[ast.Node]bool{}
+	functionMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeFunctionLiteral}}
+	for _, node := range astutil.NewNodeReader(c.ss.stmt).FindRecursive(functionMatcher) {
+		functions[node.(*ast.FunctionLiteral).GetTokens()[0]] = true
+	}
+
+	toks := []*semanticToken{}
+	add := func(from, to ast.Node, tokenType semanticTokenType, modifiers int) {
+		toks = append(toks, &semanticToken{
+			pos:       from.Pos(),
+			end:       to.End(),
+			tokenType: tokenType,
+			modifiers: modifiers,
+		})
+	}
+
+	leaves := leafNodes(c.ss.stmt)
+	for i := 0; i < len(leaves); i++ {
+		leaf := leaves[i]
+		var next ast.Node
+		if i+1 < len(leaves) && leaves[i+1].Pos() == leaf.End() {
+			next = leaves[i+1]
+		}
+
+		if ident, ok := leaf.(*ast.Identifier); ok {
+			if strings.HasPrefix(ident.String(), "@") {
+				add(ident, ident, stPlaceholder, 0)
+				continue
+			}
+			if ident.IsWildcard() {
+				continue
+			}
+			if functions[ident] && c.ss.symbolAt(ident) == nil {
+				add(ident, ident, stFunction, 0)
+				continue
+			}
+			tokenType, modifiers := c.identifierType(ident)
+			add(ident, ident, tokenType, modifiers)
+			continue
+		}
+
+		tok, ok := leaf.(ast.Token)
+		if !ok {
+			continue
+		}
+		switch tok.GetToken().Kind {
+		case token.SQLKeyword:
+			if functions[leaf] {
+				add(leaf, leaf, stFunction, 0)
+			} else if dialect.MatchKeyword(strings.ToUpper(leaf.String())) != dialect.Unmatched {
+				add(leaf, leaf, stKeyword, 0)
+			}
+		case token.Char:
+			// Placeholders are lexed as ? or as $ followed by a number
+			switch leaf.String() {
+			case "?":
+				add(leaf, leaf, stPlaceholder, 0)
+			case "$":
+				if num, ok := next.(ast.Token); ok && num.GetToken().Kind == token.Number {
+					add(leaf, next, stPlaceholder, 0)
+					i++
+				}
+			}
+		case token.Colon:
+			// :name is a placeholder, x::type a cast
+			if _, ok := next.(*ast.Identifier); ok {
+				add(leaf, next, stPlaceholder, 0)
+				i++
+			}
+		}
+	}
+	return toks
+}
+
+func (c *semanticClassifier) identifierType(ident *ast.Identifier) (semanticTokenType, int) {
+	ss := c.ss
+	if ss.create != nil && ss.create.Ident == ident {
+		switch ss.create.Kind {
+		case "FUNCTION", "PROCEDURE", "TRIGGER":
+			return stFunction, stmDeclaration
+		case "SCHEMA", "DATABASE":
+			return stNamespace, stmDeclaration
+		}
+		return stTable, stmDeclaration
+	}
+
+	if sym := ss.symbolAt(ident); sym != nil {
+		modifiers := 0
+		if sym.def == ident {
+			modifiers = stmDeclaration
+		}
+		switch sym.kind {
+		case aliasSymbolKind:
+			return stAlias, modifiers
+		case cteSymbolKind:
+			return stCTE, modifiers
+		}
+		if c.tableExists(sym.schema, sym.name) {
+			return stTable, modifiers
+		}
+		return stUnknown, 0
+	}
+
+	if ss.members[ident] != nil {
+		// A qualifier that is not a table or an alias names a schema
+		if c.dbCache == nil {
+			return stNamespace, 0
+		}
+		if _, ok := c.dbCache.Database(ident.NoQuoteString()); ok {
+			return stNamespace, 0
+		}
+		return stUnknown, 0
+	}
+
+	if col := ss.columnAt(ident); col != nil {
+		if c.columnExists(col, ident) {
+			return stColumn, 0
+		}
+		return stUnknown, 0
+	}
+	return stUnknown, 0
+}
+
+func (c *semanticClassifier) tableExists(schema, name string) bool {
+	if c.dbCache == nil || c.created[strings.ToUpper(name)] {
+		return true
+	}
+	if _, ok := c.dbCache.SchemaTables[strings.ToUpper(schema)]; schema != "" && !ok {
+		// The schema is not cached, so there is nothing to check against
+		return true
+	}
+	_, _, ok := c.dbCache.Table(schema, name)
+	return ok
+}
+
+// cachedColumns returns the columns of the table that ref names. ok is false
+// if the columns are unknown, such as for sub queries and CTEs.
+func (c *semanticClassifier) cachedColumns(ref *parseutil.TableRef) (cols []*database.ColumnDesc, ok bool) {
+	if ref.Ident == nil || c.created[strings.ToUpper(ref.Info.Name)] {
+		return nil, false
+	}
+	if c.ss.refSymbol(ref).kind != tableSymbolKind {
+		return nil, false
+	}
+	db, tbl, ok := c.dbCache.Table(ref.Info.DatabaseSchema, ref.Info.Name)
+	if !ok {
+		return nil, false
+	}
+	return c.dbCache.ColumnDatabase(db, tbl)
+}
+
+// columnExists reports whether a table that col may belong to has the
+// column. Columns that can not be checked are assumed to exist.
+func (c *semanticClassifier) columnExists(col *column, ident *ast.Identifier) bool {
+	if c.dbCache == nil {
+		return true
+	}
+	if c.ss.qualified[ident] != nil {
+		cols, ok := c.cachedColumns(col.ref)
+		return !ok || hasColumn(cols, col.name)
+	}
+
+	// Column aliases can be referred to without a qualifier
+	if len(c.ss.columnAliases().byName[strings.ToUpper(col.name)]) > 0 {
+		return true
+	}
+
+	checked := false
+	for sc := c.ss.innermostScope(ident); sc != nil; sc = sc.parent {
+		for _, ref := range sc.refs {
+			cols, ok := c.cachedColumns(ref)
+			if !ok || hasColumn(cols, col.name) {
+				return true
+			}
+			checked = true
+		}
+	}
+	return !checked
+}
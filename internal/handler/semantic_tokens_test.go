@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// decodeSemanticTokens converts the relative encoding of semantic tokens to
+// "line:col text type" strings.
+func decodeSemanticTokens(text string, data []int) []string {
+	lines := splitLines(text)
+	res := []string{}
+	line, col := 0, 0
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			col = 0
+		}
+		line += data[i]
+		col += data[i+1]
+		s := string([]rune(lines[line])[col : col+data[i+2]])
+		typ := semanticTokenLegend.TokenTypes[data[i+3]]
+		if data[i+4]&stmDeclaration != 0 {
+			typ += " declaration"
+		}
+		res = append(res, fmt.Sprintf("%d:%d %s %s", line, col, s, typ))
+	}
+	return res
+}
+
+func splitLines(text string) []string {
+	lines := []string{""}
+	for _, r := range text {
+		if r == '\n' {
+			lines = append(lines, "")
+			continue
+		}
+		lines[len(lines)-1] += string(r)
+	}
+	return lines
+}
+
+func TestSemanticTokens(t *testing.T) {
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		dbCache *database.DBCache
+		want    []string
+	}{
+		{
+			name:    "tables columns and aliases",
+			input:   "SELECT c.Name, COUNT(*) FROM world.city AS c\nWHERE c.Bogus = $1 AND Population > ?",
+			dbCache: dbCache,
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 c variable",
+				"0:9 Name property",
+				"0:15 COUNT function",
+				"0:24 FROM keyword",
+				"0:29 world namespace",
+				"0:35 city class",
+				"0:40 AS keyword",
+				"0:43 c variable declaration",
+				"1:0 WHERE keyword",
+				"1:6 c variable",
+				"1:8 Bogus unknown",
+				"1:16 $1 parameter",
+				"1:19 AND keyword",
+				"1:23 Population property",
+				"1:36 ? parameter",
+			},
+		},
+		{
+			name:    "unknown table and cte",
+			input:   "WITH x AS (SELECT ID FROM cty) SELECT Foo FROM x",
+			dbCache: dbCache,
+			want: []string{
+				"0:0 WITH keyword",
+				"0:5 x struct declaration",
+				"0:7 AS keyword",
+				"0:11 SELECT keyword",
+				"0:18 ID property",
+				"0:21 FROM keyword",
+				"0:26 cty unknown",
+				"0:31 SELECT keyword",
+				"0:38 Foo property",
+				"0:42 FROM keyword",
+				"0:47 x struct",
+			},
+		},
+		{
+			name:    "unknown column",
+			input:   "SELECT Foo FROM city",
+			dbCache: dbCache,
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 Foo unknown",
+				"0:11 FROM keyword",
+				"0:16 city class",
+			},
+		},
+		{
+			name:  "multi-line quoted identifier",
+			input: "SELECT \"Na\nme\" FROM city",
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 \"Na property",
+				"1:0 me\" property",
+				"1:4 FROM keyword",
+				"1:9 city class",
+			},
+		},
+		{
+			name:  "after a tab",
+			input: "SELECT\tID FROM city",
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 ID property",
+				"0:10 FROM keyword",
+				"0:15 city class",
+			},
+		},
+		{
+			name:    "column alias",
+			input:   "SELECT Name AS n FROM city ORDER BY n",
+			dbCache: dbCache,
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 Name property",
+				"0:12 AS keyword",
+				"0:15 n property",
+				"0:17 FROM keyword",
+				"0:22 city class",
+				"0:27 ORDER keyword",
+				"0:33 BY keyword",
+				"0:36 n property",
+			},
+		},
+		{
+			name:  "without database cache",
+			input: "SELECT Foo FROM cty WHERE id = :id",
+			want: []string{
+				"0:0 SELECT keyword",
+				"0:7 Foo property",
+				"0:11 FROM keyword",
+				"0:16 cty class",
+				"0:20 WHERE keyword",
+				"0:26 id property",
+				"0:31 :id parameter",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := semanticTokens(tt.input, nil, tt.dbCache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, decodeSemanticTokens(tt.input, got.Data)); diff != "" {
+				t.Errorf("unmatch semantic tokens (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSemanticTokensRange(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	input := "SELECT ID FROM city;\nSELECT Name FROM country;\nSELECT 1"
+	tx.textDocumentDidOpen(t, testFileURI, input)
+
+	params := lsp.SemanticTokensRangeParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+		Range: lsp.Range{
+			Start: lsp.Position{Line: 1, Character: 0},
+			End:   lsp.Position{Line: 2, Character: 0},
+		},
+	}
+	var got lsp.SemanticTokens
+	if err := tx.conn.Call(tx.ctx, "textDocument/semanticTokens/range", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/semanticTokens/range:", err)
+	}
+	want := []string{
+		"1:0 SELECT keyword",
+		"1:7 Name property",
+		"1:12 FROM keyword",
+		"1:17 country class",
+	}
+	if diff := cmp.Diff(want, decodeSemanticTokens(input, got.Data)); diff != "" {
+		t.Errorf("unmatch semantic tokens (- want, + got):\n%s", diff)
+	}
+}
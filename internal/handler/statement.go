@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// errQueryCancelled is the error of the statements cancelled by cancelQuery.
+var errQueryCancelled = errors.New("query cancelled")
+
+// serverCancelTimeout bounds the cancellation of a statement on the database
+// server.
+const serverCancelTimeout = 5 * time.Second
+
+// statement is a statement of executeQuery or explainQuery. While it runs, it is aborted by
+// the cancellation of the request, cancelQuery and the query timeout, also on
+// the database server if the driver supports it. A query keeps its
+// statement after running when its rows are left for fetchNextPage.
+type statement struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// conn is the connection the statement runs on, pinned to know its ID
+	// on the database server. It is nil if the statement can not be
+	// cancelled there.
+	conn     *sql.Conn
+	connID   int64
+	canceler database.QueryCanceler
+
+	// mu guards running and stops, and is held while the statement is
+	// cancelled on the server so that the connection is not reused before.
+	mu      sync.Mutex
+	running bool
+	stops   []func() bool
+}
+
+// newStatement returns a statement run with repo. The statement lives in the
+// context of the server, as the rows of a query may outlive the request.
+func (s *Server) newStatement(repo database.DBRepository) *statement {
+	ctx, cancel := context.WithCancelCause(s.ctx)
+	st := &statement{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	canceler, ok := repo.(database.QueryCanceler)
+	if !ok {
+		return st
+	}
+	conn, err := s.dbConn.Conn.Conn(ctx)
+	if err != nil {
+		log.Println("pin the connection of the statement:", err)
+		return st
+	}
+	id, err := canceler.ConnectionID(ctx, conn)
+	if err != nil {
+		log.Println("get the connection id of the statement:", err)
+		conn.Close()
+		return st
+	}
+	st.conn = conn
+	st.connID = id
+	st.canceler = canceler
+	return st
+}
+
+func (st *statement) query(repo database.DBRepository, query string) (*sql.Rows, error) {
+	if st.conn != nil {
+		return st.conn.QueryContext(st.ctx, query)
+	}
+	return repo.Query(st.ctx, query)
+}
+
+func (st *statement) exec(repo database.DBRepository, query string) (sql.Result, error) {
+	if st.conn != nil {
+		return st.conn.ExecContext(st.ctx, query)
+	}
+	return repo.Exec(st.ctx, query)
+}
+
+func (st *statement) explain(repo database.DBRepository, query string) ([]*database.PlanNode, error) {
+	return repo.Explain(st.ctx, st.conn, query)
+}
+
+// err returns the reason the statement was aborted for, instead of the error
+// of the driver, if it was aborted by cancelQuery or the query timeout.
+func (st *statement) err(err error) error {
+	if cause := context.Cause(st.ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return err
+}
+
+// abort cancels the statement if it is running.
+func (st *statement) abort(cause error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.running {
+		return
+	}
+	st.cancel(cause)
+	if st.canceler == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverCancelTimeout)
+	defer cancel()
+	if err := st.canceler.CancelQuery(ctx, st.connID); err != nil {
+		log.Println("cancel the query on the database server:", err)
+	}
+}
+
+// close cancels the statement and releases its connection.
+func (st *statement) close() {
+	st.cancel(context.Canceled)
+	if st.conn != nil {
+		st.conn.Close()
+	}
+}
+
+// startStatement marks st as running until stopStatement: cancelling ctx or
+// running cancelQuery aborts it, and so does the query timeout of the
+// connection. It must be called with connMu held.
+func (s *Server) startStatement(ctx context.Context, st *statement) {
+	st.mu.Lock()
+	st.running = true
+	st.stops = append(st.stops, context.AfterFunc(ctx, func() {
+		st.abort(context.Canceled)
+	}))
+	if timeout := s.curDBCfg.QueryTimeout; timeout > 0 {
+		d := time.Duration(timeout) * time.Second
+		timer := time.AfterFunc(d, func() {
+			st.abort(fmt.Errorf("query timed out after %s", d))
+		})
+		st.stops = append(st.stops, timer.Stop)
+	}
+	st.mu.Unlock()
+
+	s.statementsMu.Lock()
+	s.statements[st] = struct{}{}
+	s.statementsMu.Unlock()
+}
+
+// stopStatement marks st as no longer running.
+func (s *Server) stopStatement(st *statement) {
+	s.statementsMu.Lock()
+	delete(s.statements, st)
+	s.statementsMu.Unlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.running = false
+	for _, stop := range st.stops {
+		stop()
+	}
+	st.stops = nil
+}
+
+// cancelQuery cancels the running statements. It does not wait for the
+// commands running them, which are serialized with connMu.
+func (s *Server) cancelQuery(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
+	s.statementsMu.Lock()
+	statements := make([]*statement, 0, len(s.statements))
+	for st := range s.statements {
+		statements = append(statements, st)
+	}
+	s.statementsMu.Unlock()
+
+	for _, st := range statements {
+		st.abort(errQueryCancelled)
+	}
+	return nil, nil
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// cancelerDriver is an SQLite database whose queries are also cancelled on
+// the database server, as PostgreSQL and MySQL ones are.
+const cancelerDriver = "mock-canceler"
+
+type cancelerRepository struct {
+	database.DBRepository
+}
+
+var cancelledConnections = make(chan int64, 1)
+
+func (r *cancelerRepository) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT 42").Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *cancelerRepository) CancelQuery(ctx context.Context, id int64) error {
+	cancelledConnections <- id
+	return nil
+}
+
+func init() {
+	database.RegisterOpen(cancelerDriver, func(connCfg *database.DBConfig) (*database.DBConnection, error) {
+		cfg := *connCfg
+		cfg.Driver = dialect.DatabaseDriverSQLite3
+		return database.Open(&cfg)
+	})
+	database.RegisterFactory(cancelerDriver, func(db *sql.DB) database.DBRepository {
+		return &cancelerRepository{DBRepository: database.NewSQLite3DBRepository(db)}
+	})
+}
+
+func TestQueryTimeout(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         cancelerDriver,
+				DataSourceName: "file:query_timeout?mode=memory&cache=shared",
+				QueryTimeout:   1,
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n;")
+
+	executeCommandParams := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI, "-output=json"},
+	}
+	var got []*QueryResult
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", executeCommandParams, &got); err != nil {
+		t.Fatal("conn.Call executeQuery:", err)
+	}
+	if len(got) != 1 || got[0].Error != "query timed out after 1s" {
+		t.Errorf("unexpected results of the query timed out: %+v", got)
+	}
+
+	select {
+	case id := <-cancelledConnections:
+		if id != 42 {
+			t.Errorf("cancelled connection %d, want 42", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query was not cancelled on the database server")
+	}
+}
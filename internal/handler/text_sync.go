@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// applyContentChange applies a single didChange content change to text.
+func applyContentChange(text string, change lsp.TextDocumentContentChangeEvent) (string, error) {
+	if change.Range == nil {
+		return change.Text, nil
+	}
+	start := positionToOffset(text, change.Range.Start)
+	end := positionToOffset(text, change.Range.End)
+	if start > end {
+		return "", fmt.Errorf("invalid range %+v", *change.Range)
+	}
+	return text[:start] + change.Text + text[end:], nil
+}
+
+// positionToOffset converts an LSP position, whose character is counted in
+// UTF-16 code units, into a byte offset of text. Positions beyond the end of
+// a line or of the text are clamped, as the specification requires.
+func positionToOffset(text string, pos lsp.Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		next := lineEnd(text, offset)
+		if next == len(text) {
+			return len(text)
+		}
+		offset = skipLineBreak(text, next)
+	}
+
+	end := lineEnd(text, offset)
+	for units := 0; offset < end; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		n := utf16Len(r)
+		if units+n > pos.Character {
+			break
+		}
+		units += n
+		offset += size
+	}
+	return offset
+}
+
+// lineEnd returns the offset of the line break ending the line containing
+// offset, or the length of text for the last line.
+func lineEnd(text string, offset int) int {
+	for i := offset; i < len(text); i++ {
+		if text[i] == '\n' || text[i] == '\r' {
+			return i
+		}
+	}
+	return len(text)
+}
+
+func skipLineBreak(text string, offset int) int {
+	if text[offset] == '\r' && offset+1 < len(text) && text[offset+1] == '\n' {
+		return offset + 2
+	}
+	return offset + 1
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
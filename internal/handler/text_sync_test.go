@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestApplyContentChange(t *testing.T) {
+	rng := func(sl, sc, el, ec int) *lsp.Range {
+		return &lsp.Range{
+			Start: lsp.Position{Line: sl, Character: sc},
+			End:   lsp.Position{Line: el, Character: ec},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		rng   *lsp.Range
+		input string
+		want  string
+	}{
+		{
+			name:  "full",
+			text:  "SELECT 1",
+			rng:   nil,
+			input: "SELECT 2",
+			want:  "SELECT 2",
+		},
+		{
+			name:  "insert",
+			text:  "SELECT  FROM city",
+			rng:   rng(0, 7, 0, 7),
+			input: "ID",
+			want:  "SELECT ID FROM city",
+		},
+		{
+			name:  "replace across lines",
+			text:  "SELECT ID\nFROM city\nWHERE ID = 1",
+			rng:   rng(0, 7, 2, 5),
+			input: "*\nFROM country\nWHERE",
+			want:  "SELECT *\nFROM country\nWHERE ID = 1",
+		},
+		{
+			name:  "crlf",
+			text:  "SELECT 1;\r\nSELECT 2;",
+			rng:   rng(1, 7, 1, 8),
+			input: "3",
+			want:  "SELECT 1;\r\nSELECT 3;",
+		},
+		{
+			name:  "utf-16 surrogate pair",
+			text:  "SELECT '😀é' AS x",
+			rng:   rng(0, 10, 0, 11),
+			input: "e",
+			want:  "SELECT '😀e' AS x",
+		},
+		{
+			name:  "clamp to end of line",
+			text:  "SELECT 1\nSELECT 2",
+			rng:   rng(0, 100, 0, 100),
+			input: ";",
+			want:  "SELECT 1;\nSELECT 2",
+		},
+		{
+			name:  "append at end of document",
+			text:  "SELECT 1",
+			rng:   rng(1, 0, 1, 0),
+			input: ";",
+			want:  "SELECT 1;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := lsp.TextDocumentContentChangeEvent{
+				Range: tt.rng,
+				Text:  tt.input,
+			}
+			got, err := applyContentChange(tt.text, change)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// tableDocScheme is the URI scheme of the virtual documents describing
+// database tables. Clients fetch them with workspace/textDocumentContent.
+const tableDocScheme = "sqls"
+
+// tableDocColumnLine is the line of the first column row in database.TableDoc.
+const tableDocColumnLine = 5
+
+const maxWorkspaceSymbols = 100
+
+func (s *Server) handleWorkspaceSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.WorkspaceSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return workspaceSymbols(params.Query, s.worker.Cache()), nil
+}
+
+func (s *Server) handleWorkspaceTextDocumentContent(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.TextDocumentContentParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	text, err := tableDocContent(params.URI, s.worker.Cache())
+	if err != nil {
+		return nil, err
+	}
+	return lsp.TextDocumentContentResult{Text: text}, nil
+}
+
+type scoredSymbol struct {
+	symbol lsp.SymbolInformation
+	score  int
+}
+
+func workspaceSymbols(query string, dbCache *database.DBCache) []lsp.SymbolInformation {
+	symbols := []lsp.SymbolInformation{}
+	if dbCache == nil {
+		return symbols
+	}
+
+	scored := []*scoredSymbol{}
+	for _, schema := range dbCache.SortedSchemas() {
+		tables, _ := dbCache.SortedTablesByDBName(schema)
+		for _, table := range tables {
+			uri := tableDocURI(schema, table)
+			if score, ok := fuzzyMatch(query, table); ok {
+				scored = append(scored, &scoredSymbol{
+					symbol: lsp.SymbolInformation{
+						Name: table,
+						Kind: lsp.SKClass,
+						Location: lsp.Location{
+							URI:   uri,
+							Range: lineRange(0),
+						},
+						ContainerName: schema,
+					},
+					score: score,
+				})
+			}
+
+			cols, _ := dbCache.ColumnDatabase(schema, table)
+			for i, col := range cols {
+				score, ok := fuzzyMatch(query, col.Name)
+				if !ok {
+					continue
+				}
+				scored = append(scored, &scoredSymbol{
+					symbol: lsp.SymbolInformation{
+						Name: col.Name,
+						Kind: lsp.SKField,
+						Location: lsp.Location{
+							URI:   uri,
+							Range: lineRange(tableDocColumnLine + i),
+						},
+						ContainerName: schema + "." + table,
+					},
+					score: score,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return len(scored[i].symbol.Name) < len(scored[j].symbol.Name)
+	})
+	for _, s := range scored {
+		if len(symbols) == maxWorkspaceSymbols {
+			break
+		}
+		symbols = append(symbols, s.symbol)
+	}
+	return symbols
+}
+
+func tableDocURI(schema, table string) string {
+	return fmt.Sprintf("%s:///%s/%s.md", tableDocScheme, url.PathEscape(schema), url.PathEscape(table))
+}
+
+func tableDocContent(uri string, dbCache *database.DBCache) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Scheme != tableDocScheme || len(parts) != 2 {
+		return "", fmt.Errorf("invalid table document: %s", uri)
+	}
+	schema, table := parts[0], strings.TrimSuffix(parts[1], ".md")
+
+	if dbCache == nil {
+		return "", ErrNoConnection
+	}
+	cols, ok := dbCache.ColumnDatabase(schema, table)
+	if !ok {
+		return "", fmt.Errorf("table not found: %s.%s", schema, table)
+	}
+	return database.TableDoc(table, cols), nil
+}
+
+func lineRange(line int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: line},
+		End:   lsp.Position{Line: line},
+	}
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in name in
+// order, ignoring case. Higher scores are better matches: consecutive
+// characters and characters starting a word score more.
+func fuzzyMatch(pattern, name string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	n := []rune(name)
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	pi := 0
+	prev := -2
+	for i, r := range n {
+		if pi == len(p) {
+			break
+		}
+		if unicode.ToLower(r) != p[pi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || n[i-1] == '_' || (unicode.IsUpper(r) && unicode.IsLower(n[i-1])) {
+			score += 3
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	if strings.EqualFold(pattern, name) {
+		score += 100
+	}
+	return score, true
+}
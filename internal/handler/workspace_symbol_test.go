@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestWorkspaceSymbol(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "exact table",
+			query: "city",
+			want: []string{
+				"city world sqls:///world/city.md:0",
+			},
+		},
+		{
+			name:  "fuzzy table",
+			query: "ctrylang",
+			want: []string{
+				"countrylanguage world sqls:///world/countrylanguage.md:0",
+			},
+		},
+		{
+			name:  "fuzzy column",
+			query: "ctrcode",
+			want: []string{
+				"CountryCode world.city sqls:///world/city.md:7",
+				"CountryCode world.country sqls:///world/country.md:7",
+				"CountryCode world.countrylanguage sqls:///world/countrylanguage.md:5",
+			},
+		},
+		{
+			name:  "no match",
+			query: "xyz",
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := lsp.WorkspaceSymbolParams{
+				Query: tt.query,
+			}
+			var got []lsp.SymbolInformation
+			if err := tx.conn.Call(tx.ctx, "workspace/symbol", params, &got); err != nil {
+				t.Fatal("conn.Call workspace/symbol:", err)
+			}
+			gotStrs := []string{}
+			for _, s := range got {
+				gotStrs = append(gotStrs, fmt.Sprintf("%s %s %s:%d", s.Name, s.ContainerName, s.Location.URI, s.Location.Range.Start.Line))
+			}
+			if diff := cmp.Diff(tt.want, gotStrs); diff != "" {
+				t.Errorf("unmatch workspace symbols (- want, + got):\n%s", diff)
+			}
+		})
+	}
+
+	params := lsp.TextDocumentContentParams{
+		URI: "sqls:///world/city.md",
+	}
+	var got lsp.TextDocumentContentResult
+	if err := tx.conn.Call(tx.ctx, "workspace/textDocumentContent", params, &got); err != nil {
+		t.Fatal("conn.Call workspace/textDocumentContent:", err)
+	}
+	lines := strings.Split(got.Text, "\n")
+	if lines[0] != "# `city` table" {
+		t.Errorf("unexpected title %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[7], "| `CountryCode` |") {
+		t.Errorf("unexpected column line %q", lines[7])
+	}
+}
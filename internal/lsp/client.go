@@ -59,3 +59,57 @@ func (m *Messenger) ShowError(ctx context.Context, message string) error {
 	}
 	return m.conn.Notify(ctx, "window/showMessage", params)
 }
+
+// WorkDoneProgress reports the progress of a task of the server to the
+// client.
+type WorkDoneProgress struct {
+	conn  *jsonrpc2.Conn
+	token ProgressToken
+}
+
+// NewWorkDoneProgress creates the progress token and begins the progress
+// titled title. It must not be called from the dispatch loop, which reads the
+// response of the client.
+func NewWorkDoneProgress(ctx context.Context, conn *jsonrpc2.Conn, token ProgressToken, title string) (*WorkDoneProgress, error) {
+	params := &WorkDoneProgressCreateParams{
+		Token: token,
+	}
+	if err := conn.Call(ctx, "window/workDoneProgress/create", params, nil); err != nil {
+		return nil, err
+	}
+	p := &WorkDoneProgress{
+		conn:  conn,
+		token: token,
+	}
+	begin := &WorkDoneProgressBegin{
+		Kind:  "begin",
+		Title: title,
+	}
+	if err := p.notify(ctx, begin); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *WorkDoneProgress) Report(ctx context.Context, message string, percentage int) error {
+	return p.notify(ctx, &WorkDoneProgressReport{
+		Kind:       "report",
+		Message:    message,
+		Percentage: percentage,
+	})
+}
+
+func (p *WorkDoneProgress) End(ctx context.Context, message string) error {
+	return p.notify(ctx, &WorkDoneProgressEnd{
+		Kind:    "end",
+		Message: message,
+	})
+}
+
+func (p *WorkDoneProgress) notify(ctx context.Context, value interface{}) error {
+	params := &ProgressParams{
+		Token: p.token,
+		Value: value,
+	}
+	return p.conn.Notify(ctx, "$/progress", params)
+}
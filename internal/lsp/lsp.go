@@ -1,6 +1,8 @@
 package lsp
 
 import (
+	"github.com/sourcegraph/jsonrpc2"
+
 	"github.com/sqls-server/sqls/internal/config"
 	"github.com/sqls-server/sqls/internal/database"
 )
@@ -24,6 +26,28 @@ type InitializeOptions struct {
 }
 
 type ClientCapabilities struct {
+	TextDocument TextDocumentClientCapabilities `json:"textDocument,omitempty"`
+	Window       WindowClientCapabilities       `json:"window,omitempty"`
+}
+
+type TextDocumentClientCapabilities struct {
+	CodeAction CodeActionClientCapabilities `json:"codeAction,omitempty"`
+}
+
+type CodeActionClientCapabilities struct {
+	// CodeActionLiteralSupport is set by the clients that accept CodeAction
+	// literals. The others only accept Command.
+	CodeActionLiteralSupport *CodeActionLiteralSupport `json:"codeActionLiteralSupport,omitempty"`
+}
+
+type CodeActionLiteralSupport struct {
+	CodeActionKind struct {
+		ValueSet []CodeActionKind `json:"valueSet"`
+	} `json:"codeActionKind"`
+}
+
+type WindowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
 }
 
 type InitializeResult struct {
@@ -38,6 +62,16 @@ const (
 	TDSKIncremental TextDocumentSyncKind = 2
 )
 
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#cancelRequest
+
+type CancelParams struct {
+	ID jsonrpc2.ID `json:"id"`
+}
+
+// CodeRequestCancelled is the error code of the response to a request
+// cancelled by $/cancelRequest.
+const CodeRequestCancelled = -32800
+
 type ServerCapabilities struct {
 	TextDocumentSync                 TextDocumentSyncKind             `json:"textDocumentSync,omitempty"`
 	HoverProvider                    bool                             `json:"hoverProvider,omitempty"`
@@ -50,17 +84,25 @@ type ServerCapabilities struct {
 	DocumentHighlightProvider        bool                             `json:"documentHighlightProvider,omitempty"`
 	DocumentSymbolProvider           bool                             `json:"documentSymbolProvider,omitempty"`
 	WorkspaceSymbolProvider          bool                             `json:"workspaceSymbolProvider,omitempty"`
-	CodeActionProvider               interface{}                      `json:"codeActionProvider,omitempty"`
+	CodeActionProvider               *CodeActionOptions               `json:"codeActionProvider,omitempty"`
 	CodeLensProvider                 *CodeLensOptions                 `json:"codeLensProvider,omitempty"`
 	DocumentFormattingProvider       bool                             `json:"documentFormattingProvider,omitempty"`
 	DocumentRangeFormattingProvider  bool                             `json:"documentRangeFormattingProvider,omitempty"`
 	DocumentOnTypeFormattingProvider *DocumentOnTypeFormattingOptions `json:"documentOnTypeFormattingProvider,omitempty"`
-	RenameProvider                   bool                             `json:"renameProvider,omitempty"`
+	RenameProvider                   *RenameOptions                   `json:"renameProvider,omitempty"`
 	DocumentLinkProvider             *DocumentLinkOptions             `json:"documentLinkProvider,omitempty"`
 	ColorProvider                    bool                             `json:"colorProvider,omitempty"`
 	FoldingRangeProvider             bool                             `json:"foldingRangeProvider,omitempty"`
 	DeclarationProvider              bool                             `json:"declarationProvider,omitempty"`
 	ExecuteCommandProvider           *ExecuteCommandOptions           `json:"executeCommandProvider,omitempty"`
+	SemanticTokensProvider           *SemanticTokensOptions           `json:"semanticTokensProvider,omitempty"`
+	InlayHintProvider                bool                             `json:"inlayHintProvider,omitempty"`
+	SelectionRangeProvider           bool                             `json:"selectionRangeProvider,omitempty"`
+	Workspace                        *WorkspaceOptions                `json:"workspace,omitempty"`
+}
+
+type WorkspaceOptions struct {
+	TextDocumentContent *TextDocumentContentOptions `json:"textDocumentContent,omitempty"`
 }
 
 type CompletionOptions struct {
@@ -75,12 +117,17 @@ type SignatureHelpOptions struct {
 }
 
 type CodeActionOptions struct {
-	CodeActionKinds []CodeActionKind
+	CodeActionKinds []CodeActionKind `json:"codeActionKinds,omitempty"`
 }
 
-type CodeLensOptions struct{}
+type CodeLensOptions struct {
+	ResolveProvider bool `json:"resolveProvider,omitempty"`
+}
 
-type DocumentOnTypeFormattingOptions struct{}
+type DocumentOnTypeFormattingOptions struct {
+	FirstTriggerCharacter string   `json:"firstTriggerCharacter"`
+	MoreTriggerCharacter  []string `json:"moreTriggerCharacter,omitempty"`
+}
 
 type DocumentLinkOptions struct{}
 
@@ -117,6 +164,14 @@ type DidCloseTextDocumentParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
 
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-14/#textDocument_publishDiagnostics
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-14/#textDocument_completion
 
 type CompletionParams struct {
@@ -231,8 +286,9 @@ type TextDocumentIdentifier struct {
 }
 
 type TextDocumentContentChangeEvent struct {
-	Range       Range  `json:"range"`
-	RangeLength int    `json:"rangeLength"`
+	// Range is nil when Text is the full content of the document
+	Range       *Range `json:"range,omitempty"`
+	RangeLength int    `json:"rangeLength,omitempty"`
 	Text        string `json:"text"`
 }
 
@@ -253,6 +309,11 @@ type TextDocumentPositionParams struct {
 
 type CodeActionKind string
 
+const (
+	CAKQuickFix CodeActionKind = "quickfix"
+	CAKSource   CodeActionKind = "source"
+)
+
 type Location struct {
 	URI   string `json:"uri"`
 	Range Range  `json:"range"`
@@ -263,9 +324,18 @@ type DiagnosticRelatedInformation struct {
 	Message  string   `json:"message"`
 }
 
+type DiagnosticSeverity int
+
+const (
+	DSError       DiagnosticSeverity = 1
+	DSWarning     DiagnosticSeverity = 2
+	DSInformation DiagnosticSeverity = 3
+	DSHint        DiagnosticSeverity = 4
+)
+
 type Diagnostic struct {
 	Range              Range                          `json:"range"`
-	Severity           int                            `json:"severity,omitempty"`
+	Severity           DiagnosticSeverity             `json:"severity,omitempty"`
 	Code               *string                        `json:"code,omitempty"`
 	Source             *string                        `json:"source,omitempty"`
 	Message            string                         `json:"message"`
@@ -276,6 +346,39 @@ type WorkDoneProgressParams struct {
 	WorkDoneToken interface{} `json:"workDoneToken"`
 }
 
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#progress
+
+type ProgressToken interface{}
+
+type ProgressParams struct {
+	Token ProgressToken `json:"token"`
+	Value interface{}   `json:"value"`
+}
+
+type WorkDoneProgressCreateParams struct {
+	Token ProgressToken `json:"token"`
+}
+
+type WorkDoneProgressBegin struct {
+	Kind        string `json:"kind"`
+	Title       string `json:"title"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  int    `json:"percentage"`
+}
+
+type WorkDoneProgressReport struct {
+	Kind        string `json:"kind"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  int    `json:"percentage"`
+}
+
+type WorkDoneProgressEnd struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+}
+
 type CodeActionContext struct {
 	Diagnostics []Diagnostic     `json:"diagnostics"`
 	Only        []CodeActionKind `json:"only,omitempty"`
@@ -294,6 +397,15 @@ type CodeActionParams struct {
 	Context      CodeActionContext      `json:"context"`
 }
 
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+	Command     *Command       `json:"command,omitempty"`
+}
+
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-14/#workspace_executeCommand
 
 type ExecuteCommandParams struct {
@@ -358,6 +470,12 @@ type DocumentRangeFormattingParams struct {
 	WorkDoneProgressParams
 }
 
+type DocumentOnTypeFormattingParams struct {
+	TextDocumentPositionParams
+	Ch      string            `json:"ch"`
+	Options FormattingOptions `json:"options"`
+}
+
 type ParameterInformation struct {
 	Label         string `json:"label"`
 	Documentation string `json:"documentation,omitempty"`
@@ -434,6 +552,16 @@ type RenameParams struct {
 	WorkDoneProgressParams
 }
 
+type PrepareRenameParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+}
+
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
 type RenameFile struct {
 	Kind    string            `json:"kind"`
 	OldURI  DocumentURI       `json:"oldUri"`
@@ -507,3 +635,220 @@ type TypeDefinitionParams struct {
 }
 
 type Definition = []Location
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_documentSymbol
+
+type SymbolKind int
+
+const (
+	SKFile          SymbolKind = 1
+	SKModule        SymbolKind = 2
+	SKNamespace     SymbolKind = 3
+	SKPackage       SymbolKind = 4
+	SKClass         SymbolKind = 5
+	SKMethod        SymbolKind = 6
+	SKProperty      SymbolKind = 7
+	SKField         SymbolKind = 8
+	SKConstructor   SymbolKind = 9
+	SKEnum          SymbolKind = 10
+	SKInterface     SymbolKind = 11
+	SKFunction      SymbolKind = 12
+	SKVariable      SymbolKind = 13
+	SKConstant      SymbolKind = 14
+	SKString        SymbolKind = 15
+	SKNumber        SymbolKind = 16
+	SKBoolean       SymbolKind = 17
+	SKArray         SymbolKind = 18
+	SKObject        SymbolKind = 19
+	SKKey           SymbolKind = 20
+	SKNull          SymbolKind = 21
+	SKEnumMember    SymbolKind = 22
+	SKStruct        SymbolKind = 23
+	SKEvent         SymbolKind = 24
+	SKOperator      SymbolKind = 25
+	SKTypeParameter SymbolKind = 26
+)
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#workspace_symbol
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_textDocumentContent
+
+type TextDocumentContentOptions struct {
+	Schemes []string `json:"schemes"`
+}
+
+type TextDocumentContentParams struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentContentResult struct {
+	Text string `json:"text"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_references
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_documentHighlight
+
+type DocumentHighlightParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type DocumentHighlightKind int
+
+const (
+	DHKText  DocumentHighlightKind = 1
+	DHKRead  DocumentHighlightKind = 2
+	DHKWrite DocumentHighlightKind = 3
+)
+
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_foldingRange
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type FoldingRangeKind string
+
+const (
+	FRKComment FoldingRangeKind = "comment"
+	FRKImports FoldingRangeKind = "imports"
+	FRKRegion  FoldingRangeKind = "region"
+)
+
+type FoldingRange struct {
+	StartLine int              `json:"startLine"`
+	EndLine   int              `json:"endLine"`
+	Kind      FoldingRangeKind `json:"kind,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_selectionRange
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_semanticTokens
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range,omitempty"`
+	Full   bool                 `json:"full,omitempty"`
+	WorkDoneProgressOptions
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SemanticTokens struct {
+	ResultID string `json:"resultId,omitempty"`
+	Data     []int  `json:"data"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_inlayHint
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	WorkDoneProgressParams
+}
+
+type InlayHintKind int
+
+const (
+	IHKType      InlayHintKind = 1
+	IHKParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind,omitempty"`
+	Tooltip      string        `json:"tooltip,omitempty"`
+	PaddingLeft  bool          `json:"paddingLeft,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_codeLens
+
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type CodeLens struct {
+	Range   Range       `json:"range"`
+	Command *Command    `json:"command,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
@@ -0,0 +1,9 @@
+package main
+
+import "net"
+
+// listenUnix listens on a unix socket. Windows does not apply the file mode to
+// sockets, which are protected by the permissions of their directory.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
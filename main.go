@@ -6,15 +6,20 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/urfave/cli/v2"
 
 	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/handler"
 )
 
@@ -53,6 +58,14 @@ func realMain() error {
 				Aliases: []string{"t"},
 				Usage:   "Print all requests and responses.",
 			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "Listen on tcp://host:port or unix:///path instead of stdin/stdout, serving each client with its own server. Clients are not authenticated, so TCP addresses must be loopback ones.",
+			},
+			&cli.BoolFlag{
+				Name:  "listen-allow-remote",
+				Usage: "Allow --listen on a TCP address that is not a loopback one. Anyone who can connect runs queries on your connections.",
+			},
 		},
 		Commands: cli.Commands{
 			{
@@ -95,6 +108,8 @@ func serve(c *cli.Context) error {
 	logfile := c.String("log")
 	configFile := c.String("config")
 	trace := c.Bool("trace")
+	listenAddr := c.String("listen")
+	allowRemote := c.Bool("listen-allow-remote")
 
 	// Initialize log writer
 	var logWriter io.Writer
@@ -110,48 +125,145 @@ func serve(c *cli.Context) error {
 	}
 	log.SetOutput(logWriter)
 
+	// Set connect option
+	var connOpt []jsonrpc2.ConnOpt
+	if trace {
+		connOpt = append(connOpt, jsonrpc2.LogMessages(log.New(logWriter, "", 0)))
+	}
+
+	if listenAddr != "" {
+		return serveListener(listenAddr, allowRemote, configFile, connOpt)
+	}
+
 	// Initialize language server
-	server := handler.NewServer()
+	server, err := newServer(configFile)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if err := server.Stop(); err != nil {
 			log.Println(err)
 		}
 	}()
-	h := jsonrpc2.HandlerWithError(server.Handle)
+	h := server.Handler()
+
+	// Start language server
+	log.Println("sqls: reading on stdin, writing on stdout")
+	<-jsonrpc2.NewConn(
+		context.Background(),
+		jsonrpc2.NewBufferedStream(stdrwc{}, jsonrpc2.VSCodeObjectCodec{}),
+		h,
+		connOpt...,
+	).DisconnectNotify()
+	log.Println("sqls: connections closed")
+
+	return nil
+}
+
+// newServer returns a language server with its own copy of the config files,
+// which the server modifies when switching connections.
+func newServer(configFile string) (*handler.Server, error) {
+	server := handler.NewServer()
 
 	// Load specific config
 	if configFile != "" {
 		cfg, err := config.GetConfig(configFile)
 		if err != nil {
-			return fmt.Errorf("cannot read specified config, %w", err)
+			return nil, fmt.Errorf("cannot read specified config, %w", err)
 		}
 		server.SpecificFileCfg = cfg
 	} else {
 		// Load default config
 		cfg, err := config.GetDefaultConfig()
 		if err != nil && !errors.Is(config.ErrNotFoundConfig, err) {
-			return fmt.Errorf("cannot read default config, %w", err)
+			return nil, fmt.Errorf("cannot read default config, %w", err)
 		}
 		server.DefaultFileCfg = cfg
 	}
+	return server, nil
+}
 
-	// Set connect option
-	var connOpt []jsonrpc2.ConnOpt
-	if trace {
-		connOpt = append(connOpt, jsonrpc2.LogMessages(log.New(logWriter, "", 0)))
+// serveListener serves each client connecting to addr with its own language
+// server, until interrupted. The servers share the database caches.
+func serveListener(addr string, allowRemote bool, configFile string, connOpt []jsonrpc2.ConnOpt) error {
+	lis, err := listen(addr, allowRemote)
+	if err != nil {
+		return err
 	}
+	defer lis.Close()
 
-	// Start language server
-	log.Println("sqls: reading on stdin, writing on stdout")
-	<-jsonrpc2.NewConn(
-		context.Background(),
-		jsonrpc2.NewBufferedStream(stdrwc{}, jsonrpc2.VSCodeObjectCodec{}),
-		h,
-		connOpt...,
-	).DisconnectNotify()
-	log.Println("sqls: connections closed")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		lis.Close()
+	}()
 
-	return nil
+	caches := database.NewCacheStore()
+	log.Printf("sqls: listening on %s", addr)
+	for id := 1; ; id++ {
+		nc, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("sqls: listener closed")
+				return nil
+			}
+			return err
+		}
+
+		server, err := newServer(configFile)
+		if err != nil {
+			log.Println(err)
+			nc.Close()
+			continue
+		}
+		server.Caches = caches
+		go func(id int) {
+			defer func() {
+				if err := server.Stop(); err != nil {
+					log.Println(err)
+				}
+			}()
+			log.Printf("sqls: client %d connected", id)
+			<-jsonrpc2.NewConn(
+				ctx,
+				jsonrpc2.NewBufferedStream(nc, jsonrpc2.VSCodeObjectCodec{}),
+				server.Handler(),
+				connOpt...,
+			).DisconnectNotify()
+			log.Printf("sqls: client %d disconnected", id)
+		}(id)
+	}
+}
+
+// listen listens on a tcp://host:port or unix:///path address. As the
+// clients are not authenticated, a TCP address must be a loopback one unless
+// allowRemote is set, and the unix socket is only accessible to its owner.
+func listen(addr string, allowRemote bool) (net.Listener, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q, %w", addr, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if !allowRemote && !isLoopback(u.Hostname()) {
+			return nil, fmt.Errorf("refusing to listen on %q, which is not a loopback address: clients are not authenticated, use --listen-allow-remote to listen anyway", addr)
+		}
+		return net.Listen("tcp", u.Host)
+	case "unix":
+		return listenUnix(u.Path)
+	}
+	return nil, fmt.Errorf("invalid listen address %q, expected tcp://host:port or unix:///path", addr)
+}
+
+// isLoopback reports whether host only accepts local connections. An empty
+// host listens on all the interfaces.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 type stdrwc struct{}
@@ -0,0 +1,84 @@
+package parseutil
+
+import (
+	"strings"
+
+	"github.com/sqls-server/sqls/ast"
+)
+
+// CreateInfo is the object defined by a CREATE statement.
+type CreateInfo struct {
+	// Kind is the kind of the created object such as TABLE, VIEW or INDEX.
+	Kind           string
+	DatabaseSchema string
+	Name           string
+	Ident          *ast.Identifier
+}
+
+var createModifiers = map[string]bool{
+	"OR":           true,
+	"REPLACE":      true,
+	"TEMP":         true,
+	"TEMPORARY":    true,
+	"GLOBAL":       true,
+	"LOCAL":        true,
+	"UNLOGGED":     true,
+	"UNIQUE":       true,
+	"MATERIALIZED": true,
+	"RECURSIVE":    true,
+}
+
+// ExtractCreate returns the object created by stmt, or nil if stmt is not a
+// CREATE statement.
+func ExtractCreate(stmt ast.TokenList) *CreateInfo {
+	nodes := []ast.Node{}
+	for _, node := range significantNodes(stmt) {
+		// CREATE INDEX name is parsed as an aliased identifier
+		if aliased, ok := node.(*ast.Aliased); ok {
+			nodes = append(nodes, significantNodes(aliased)...)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 || !isKeyword(nodes[0], "CREATE") {
+		return nil
+	}
+
+	i := 1
+	for i < len(nodes) && createModifiers[strings.ToUpper(nodes[i].String())] {
+		i++
+	}
+	if i >= len(nodes) {
+		return nil
+	}
+	info := &CreateInfo{Kind: strings.ToUpper(nodes[i].String())}
+	i++
+
+	if i+2 < len(nodes) && isKeyword(nodes[i], "IF") && isKeyword(nodes[i+1], "NOT") && isKeyword(nodes[i+2], "EXISTS") {
+		i += 3
+	}
+	if i >= len(nodes) {
+		return nil
+	}
+
+	name := nodes[i]
+	// CREATE TABLE name(col type, ...) is parsed as a function call
+	if fl, ok := name.(*ast.FunctionLiteral); ok {
+		name = fl.GetTokens()[0]
+	}
+	switch v := name.(type) {
+	case *ast.Identifier:
+		info.Name = v.NoQuoteString()
+		info.Ident = v
+	case *ast.MemberIdentifier:
+		if v.ParentIdent == nil || v.ChildIdent == nil {
+			return nil
+		}
+		info.DatabaseSchema = v.ParentIdent.NoQuoteString()
+		info.Name = v.ChildIdent.NoQuoteString()
+		info.Ident = v.ChildIdent
+	default:
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,52 @@
+package parseutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sqls-server/sqls/ast"
+)
+
+func TestExtractCreate(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  *CreateInfo
+	}{
+		{
+			name:  "table",
+			input: "CREATE TABLE foo (id int)",
+			want:  &CreateInfo{Kind: "TABLE", Name: "foo"},
+		},
+		{
+			name:  "table without space",
+			input: "CREATE TEMPORARY TABLE IF NOT EXISTS foo(id int)",
+			want:  &CreateInfo{Kind: "TABLE", Name: "foo"},
+		},
+		{
+			name:  "view with schema",
+			input: "CREATE OR REPLACE VIEW sch.foo AS SELECT 1",
+			want:  &CreateInfo{Kind: "VIEW", DatabaseSchema: "sch", Name: "foo"},
+		},
+		{
+			name:  "index",
+			input: "CREATE UNIQUE INDEX ix ON foo (id)",
+			want:  &CreateInfo{Kind: "INDEX", Name: "ix"},
+		},
+		{
+			name:  "not create",
+			input: "SELECT 1",
+			want:  nil,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			query := initExtractTable(t, tt.input)
+			got := ExtractCreate(query.GetTokens()[0].(ast.TokenList))
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(CreateInfo{}, "Ident")); diff != "" {
+				t.Errorf("unmatched create info (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package parseutil
+
+import (
+	"github.com/sqls-server/sqls/ast"
+)
+
+// CTEInfo is a common table expression defined by the WITH clause of a
+// statement.
+type CTEInfo struct {
+	Name  string
+	Ident *ast.Identifier
+	// Columns is the optional column list following the name.
+	Columns *ast.Parenthesis
+	Query   *ast.Parenthesis
+}
+
+// ExtractCTEs returns the common table expressions defined at the head of
+// stmt.
+func ExtractCTEs(stmt ast.TokenList) []*CTEInfo {
+	nodes := significantNodes(stmt)
+	ctes := []*CTEInfo{}
+	if len(nodes) == 0 || !isKeyword(nodes[0], "WITH") {
+		return ctes
+	}
+	i := 1
+	if i < len(nodes) && isKeyword(nodes[i], "RECURSIVE") {
+		i++
+	}
+	for i < len(nodes) {
+		cte := &CTEInfo{}
+		switch v := nodes[i].(type) {
+		case *ast.Identifier:
+			cte.Ident = v
+		case *ast.FunctionLiteral:
+			// name(col, ...) is parsed as a function call
+			toks := v.GetTokens()
+			if len(toks) != 2 {
+				return ctes
+			}
+			ident, ok := toks[0].(*ast.Identifier)
+			if !ok {
+				return ctes
+			}
+			cte.Ident = ident
+			cte.Columns, _ = toks[1].(*ast.Parenthesis)
+		default:
+			return ctes
+		}
+		cte.Name = cte.Ident.NoQuoteString()
+		i++
+
+		if i < len(nodes) && isKeyword(nodes[i], "AS") {
+			i++
+		}
+		if i >= len(nodes) {
+			return ctes
+		}
+		query, ok := nodes[i].(*ast.Parenthesis)
+		if !ok {
+			return ctes
+		}
+		cte.Query = query
+		ctes = append(ctes, cte)
+		i++
+
+		if i >= len(nodes) || nodes[i].String() != "," {
+			return ctes
+		}
+		i++
+	}
+	return ctes
+}
+
+func isKeyword(node ast.Node, keyword string) bool {
+	matcher := genKeywordMatcher([]string{keyword})
+	return matcher.IsMatchKeyword(node)
+}
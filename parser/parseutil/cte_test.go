@@ -0,0 +1,58 @@
+package parseutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/ast"
+)
+
+func TestExtractCTEs(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		want    []string
+		columns []string
+	}{
+		{
+			name:    "single",
+			input:   "WITH x AS (SELECT 1) SELECT * FROM x",
+			want:    []string{"x"},
+			columns: []string{""},
+		},
+		{
+			name:    "multiple with columns",
+			input:   "WITH RECURSIVE x AS (SELECT 1), y(a, b) AS (SELECT 1, 2) SELECT * FROM x, y",
+			want:    []string{"x", "y"},
+			columns: []string{"", "(a, b)"},
+		},
+		{
+			name:    "no cte",
+			input:   "SELECT * FROM x",
+			want:    []string{},
+			columns: []string{},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			query := initExtractTable(t, tt.input)
+			stmt := query.GetTokens()[0].(ast.TokenList)
+			got := []string{}
+			columns := []string{}
+			for _, cte := range ExtractCTEs(stmt) {
+				got = append(got, cte.Name)
+				if cte.Columns != nil {
+					columns = append(columns, cte.Columns.String())
+				} else {
+					columns = append(columns, "")
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatched names (- want, + got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.columns, columns); diff != "" {
+				t.Errorf("unmatched columns (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -193,6 +193,18 @@ func parseInsertColumns(reader *astutil.NodeReader) []ast.Node {
 }
 
 func ExtractInsertValues(parsed ast.TokenList, pos token.Pos) []ast.Node {
+	values := ExtractAllInsertValues(parsed)
+	for _, v := range values {
+		if astutil.IsEnclose(v, pos) {
+			return []ast.Node{v}
+		}
+	}
+	return []ast.Node{}
+}
+
+// ExtractAllInsertValues returns the value lists of every row of the VALUES
+// clause.
+func ExtractAllInsertValues(parsed ast.TokenList) []ast.Node {
 	insertTableIdentifier := astutil.NodeMatcher{
 		ExpectTokens: []token.Kind{
 			token.Comma,
@@ -201,13 +213,7 @@ func ExtractInsertValues(parsed ast.TokenList, pos token.Pos) []ast.Node {
 			"VALUES",
 		},
 	}
-	values := parsePrefix(astutil.NewNodeReader(parsed), insertTableIdentifier, parseInsertValues)
-	for _, v := range values {
-		if astutil.IsEnclose(v, pos) {
-			return []ast.Node{v}
-		}
-	}
-	return []ast.Node{}
+	return parsePrefix(astutil.NewNodeReader(parsed), insertTableIdentifier, parseInsertValues)
 }
 
 func parseInsertValues(reader *astutil.NodeReader) []ast.Node {
@@ -254,3 +260,20 @@ func filterPrefixGroupOnce(reader *astutil.NodeReader, prefixMatcher astutil.Nod
 	}
 	return nil
 }
+
+// ExtractSubQueryAliases returns the aliased sub queries in parsed. Sub
+// queries nested inside the returned ones are not included.
+func ExtractSubQueryAliases(parsed ast.TokenList) []*ast.Aliased {
+	results := []*ast.Aliased{}
+	reader := astutil.NewNodeReader(parsed)
+	for reader.NextNode(false) {
+		if isSubQueryByNode(reader.CurNode) {
+			results = append(results, reader.CurNode.(*ast.Aliased))
+			continue
+		}
+		if list, ok := reader.CurNode.(ast.TokenList); ok {
+			results = append(results, ExtractSubQueryAliases(list)...)
+		}
+	}
+	return results
+}
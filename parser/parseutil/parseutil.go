@@ -555,3 +555,32 @@ func aliasedToSubQueryColumn(aliased *ast.Aliased) (*SubQueryColumn, error) {
 		)
 	}
 }
+
+// significantNodes returns the direct children of list without whitespace
+// and comments.
+func significantNodes(list ast.TokenList) []ast.Node {
+	nodes := []ast.Node{}
+	for _, node := range list.GetTokens() {
+		if tok, ok := node.(ast.Token); ok {
+			switch tok.GetToken().Kind {
+			case token.Whitespace, token.Comment, token.MultilineComment:
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// IsSubQuery reports whether node is a parenthesis enclosing a query.
+func IsSubQuery(node ast.Node) bool {
+	parenthesis, ok := node.(*ast.Parenthesis)
+	if !ok {
+		return false
+	}
+	nodes := significantNodes(parenthesis.Inner())
+	if len(nodes) == 0 {
+		return false
+	}
+	return isKeyword(nodes[0], "SELECT") || isKeyword(nodes[0], "WITH")
+}
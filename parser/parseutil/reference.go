@@ -0,0 +1,136 @@
+package parseutil
+
+import (
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+)
+
+// TableRef is a table referenced by a statement, together with the node
+// naming the table in the source text.
+type TableRef struct {
+	Info *TableInfo
+	// Ident is the identifier of the table name. It is nil when the
+	// reference is an aliased sub query.
+	Ident *ast.Identifier
+	// AliasIdent is the identifier of the alias, nil if there is no alias.
+	AliasIdent *ast.Identifier
+	// Node is the whole reference, including the schema and the alias.
+	Node ast.Node
+}
+
+var tableRefPrefixMatcher = astutil.NodeMatcher{
+	ExpectKeyword: []string{
+		"FROM",
+		"UPDATE",
+		"INSERT INTO",
+		"DELETE FROM",
+		"JOIN",
+		"INNER JOIN",
+		"CROSS JOIN",
+		"OUTER JOIN",
+		"LEFT JOIN",
+		"RIGHT JOIN",
+		"LEFT OUTER JOIN",
+		"RIGHT OUTER JOIN",
+	},
+}
+
+var insertIntoMatcher = genKeywordMatcher([]string{"INSERT INTO"})
+
+// ExtractTableRefs returns every table referenced in parsed, including the
+// tables of sub queries. Unlike ExtractTable it does not depend on a cursor
+// position and keeps the nodes so that callers can report ranges.
+func ExtractTableRefs(parsed ast.TokenList) []*TableRef {
+	refs := []*TableRef{}
+	for _, node := range filterTableRefNodes(astutil.NewNodeReader(parsed)) {
+		refs = append(refs, nodeToTableRefs(node)...)
+	}
+	return refs
+}
+
+func filterTableRefNodes(reader *astutil.NodeReader) []ast.Node {
+	var results []ast.Node
+	for reader.NextNode(false) {
+		// IS DISTINCT FROM is a comparison, not a from clause
+		if reader.CurNodeIs(tableRefPrefixMatcher) &&
+			!reader.PrevNodeIs(true, genKeywordMatcher([]string{"DISTINCT"})) &&
+			reader.PeekNodeIs(true, identifierMatcher) {
+			_, node := reader.PeekNode(true)
+			results = append(results, node)
+		}
+		// INSERT INTO city(ID, Name) parses the table name and the column
+		// list as a function
+		if reader.CurNodeIs(insertIntoMatcher) {
+			if _, node := reader.PeekNode(true); node != nil {
+				if fl, ok := node.(*ast.FunctionLiteral); ok {
+					if ident, ok := fl.GetTokens()[0].(*ast.Identifier); ok {
+						results = append(results, ident)
+					}
+				}
+			}
+		}
+		// Function arguments such as EXTRACT(YEAR FROM d) never refer to
+		// tables, but sub queries such as EXISTS (SELECT ...) do
+		if fl, ok := reader.CurNode.(*ast.FunctionLiteral); ok {
+			for _, arg := range fl.GetTokens() {
+				if IsSubQuery(arg) {
+					results = append(results, filterTableRefNodes(astutil.NewNodeReader(arg.(ast.TokenList)))...)
+				}
+			}
+			continue
+		}
+		if list, ok := reader.CurNode.(ast.TokenList); ok {
+			results = append(results, filterTableRefNodes(astutil.NewNodeReader(list))...)
+		}
+	}
+	return results
+}
+
+func nodeToTableRefs(node ast.Node) []*TableRef {
+	switch v := node.(type) {
+	case *ast.Identifier:
+		return []*TableRef{{
+			Info:  &TableInfo{Name: v.NoQuoteString()},
+			Ident: v,
+			Node:  v,
+		}}
+	case *ast.MemberIdentifier:
+		if v.ParentIdent == nil || v.ChildIdent == nil {
+			return nil
+		}
+		return []*TableRef{{
+			Info: &TableInfo{
+				DatabaseSchema: v.ParentIdent.NoQuoteString(),
+				Name:           v.ChildIdent.NoQuoteString(),
+			},
+			Ident: v.ChildIdent,
+			Node:  v,
+		}}
+	case *ast.Aliased:
+		aliasIdent, ok := v.AliasedName.(*ast.Identifier)
+		if !ok {
+			return nil
+		}
+		if _, ok := v.RealName.(*ast.Parenthesis); ok {
+			return []*TableRef{{
+				Info:       &TableInfo{Alias: aliasIdent.NoQuoteString()},
+				AliasIdent: aliasIdent,
+				Node:       v,
+			}}
+		}
+		refs := nodeToTableRefs(v.RealName)
+		for _, ref := range refs {
+			ref.Info.Alias = aliasIdent.NoQuoteString()
+			ref.AliasIdent = aliasIdent
+			ref.Node = v
+		}
+		return refs
+	case *ast.IdentifierList:
+		refs := []*TableRef{}
+		for _, ident := range v.GetIdentifiers() {
+			refs = append(refs, nodeToTableRefs(ident)...)
+		}
+		return refs
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package parseutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractTableRefs(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  []*TableInfo
+	}{
+		{
+			name:  "from and join",
+			input: "SELECT * FROM abc a LEFT JOIN sch.def AS d ON a.id = d.id",
+			want: []*TableInfo{
+				{Name: "abc", Alias: "a"},
+				{DatabaseSchema: "sch", Name: "def", Alias: "d"},
+			},
+		},
+		{
+			name:  "identifier list",
+			input: "SELECT * FROM abc, def d",
+			want: []*TableInfo{
+				{Name: "abc"},
+				{Name: "def", Alias: "d"},
+			},
+		},
+		{
+			name:  "sub query",
+			input: "SELECT * FROM (SELECT * FROM abc) AS sub WHERE id IN (SELECT id FROM def)",
+			want: []*TableInfo{
+				{Alias: "sub"},
+				{Name: "abc"},
+				{Name: "def"},
+			},
+		},
+		{
+			name:  "insert update delete",
+			input: "INSERT INTO abc (id) VALUES (1); UPDATE def SET id = 1; DELETE FROM ghi",
+			want: []*TableInfo{
+				{Name: "abc"},
+				{Name: "def"},
+				{Name: "ghi"},
+			},
+		},
+		{
+			name:  "insert column list without space",
+			input: "INSERT INTO abc(id) SELECT id FROM def",
+			want: []*TableInfo{
+				{Name: "abc"},
+				{Name: "def"},
+			},
+		},
+		{
+			name:  "exists sub query",
+			input: "SELECT * FROM abc WHERE EXISTS (SELECT 1 FROM def)",
+			want: []*TableInfo{
+				{Name: "abc"},
+				{Name: "def"},
+			},
+		},
+		{
+			name:  "not a table",
+			input: "SELECT EXTRACT(YEAR FROM d) FROM abc WHERE a IS DISTINCT FROM b",
+			want: []*TableInfo{
+				{Name: "abc"},
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			query := initExtractTable(t, tt.input)
+			got := []*TableInfo{}
+			for _, ref := range ExtractTableRefs(query) {
+				got = append(got, ref.Info)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatched table refs (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/sqls-server/sqls/dialect"
 )
 
+var ErrUnclosedMultilineComment = errors.New("unclosed multiline comment")
+
 type SQLWord struct {
 	Value      string
 	QuoteStyle rune
@@ -107,6 +109,63 @@ func ComparePos(x, y Pos) int {
 	return -1
 }
 
+// tabWidth is the number of columns that a tab advances the tokenizer by.
+const tabWidth = 4
+
+// UTF16Col converts the column col of the tokenizer on line to the number of
+// UTF-16 code units before it, which LSP positions count. The tokenizer counts
+// a tab as tabWidth columns and any other character as one.
+func UTF16Col(line string, col int) int {
+	c, u := 0, 0
+	for _, r := range line {
+		if c >= col {
+			return u
+		}
+		if r == '\t' {
+			c += tabWidth
+		} else {
+			c++
+		}
+		if r >= 0x10000 {
+			// Encoded as a surrogate pair
+			u += 2
+		} else {
+			u++
+		}
+	}
+	// Columns past the end of the line, such as the end of a statement
+	// including its line break, are kept past it
+	if col > c {
+		u += col - c
+	}
+	return u
+}
+
+// ColOfUTF16 converts the UTF-16 column u of line, as LSP positions count
+// it, to the column of the tokenizer. It is the inverse of UTF16Col.
+func ColOfUTF16(line string, u int) int {
+	c, n := 0, 0
+	for _, r := range line {
+		if n >= u {
+			return c
+		}
+		if r == '\t' {
+			c += tabWidth
+		} else {
+			c++
+		}
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	if u > n {
+		c += u - n
+	}
+	return c
+}
+
 type Tokenizer struct {
 	Dialect dialect.Dialect
 	Scanner *scanner.Scanner
@@ -171,7 +230,7 @@ func (t *Tokenizer) next() (Kind, interface{}, error) {
 
 	case r == '\t':
 		t.Scanner.Next()
-		t.Col += 4
+		t.Col += tabWidth
 		return Whitespace, "\t", nil
 
 	case r == '\n':
@@ -406,16 +465,16 @@ func (t *Tokenizer) tokenizeWord(f rune) string {
 
 func (t *Tokenizer) tokenizeSingleQuotedString() string {
 	var str []rune
-	t.Scanner.Next()
+	t.advance(t.Scanner.Next())
 	isClosed := false
 
 	for {
 		n := t.Scanner.Peek()
 		if n == '\'' {
-			t.Scanner.Next()
+			t.advance(t.Scanner.Next())
 			if t.Scanner.Peek() == '\'' {
 				str = append(str, '\'')
-				t.Scanner.Next()
+				t.advance(t.Scanner.Next())
 			} else {
 				isClosed = true
 				break
@@ -426,20 +485,18 @@ func (t *Tokenizer) tokenizeSingleQuotedString() string {
 			break
 		}
 
-		t.Scanner.Next()
+		t.advance(t.Scanner.Next())
 		str = append(str, n)
 	}
 
 	if isClosed {
-		t.Col += 2 + len(str)
 		return "'" + string(str) + "'"
 	}
-	t.Col += 1 + len(str)
 	return "'" + string(str)
 }
 
 func (t *Tokenizer) tokenizeDelimitedIdentifier(r rune) *SQLWord {
-	t.Scanner.Next()
+	t.advance(t.Scanner.Next())
 	end := matchingEndQuote(r)
 	isClosed := false
 
@@ -449,6 +506,7 @@ func (t *Tokenizer) tokenizeDelimitedIdentifier(r rune) *SQLWord {
 		if n == scanner.EOF {
 			break
 		}
+		t.advance(n)
 		if n == end {
 			isClosed = true
 			break
@@ -460,13 +518,32 @@ func (t *Tokenizer) tokenizeDelimitedIdentifier(r rune) *SQLWord {
 	}
 
 	if isClosed {
-		t.Col += 2 + len(s)
 		return MakeKeyword(string(s), r)
 	}
-	t.Col += 1 + len(s)
 	return MakeKeyword(string(r)+string(s), 0)
 }
 
+// advance moves the position past r, read from the scanner, so that the
+// tokens after a quoted string or identifier spanning lines are positioned
+// on the right line.
+func (t *Tokenizer) advance(r rune) {
+	switch r {
+	case '\n':
+		t.Line++
+		t.Col = 0
+	case '\r':
+		// A CRLF line break is counted on the LF
+		if t.Scanner.Peek() != '\n' {
+			t.Line++
+			t.Col = 0
+		}
+	case '\t':
+		t.Col += tabWidth
+	default:
+		t.Col++
+	}
+}
+
 func (t *Tokenizer) tokenizeMultilineComment() (string, error) {
 	var str []rune
 	var mayBeClosingComment bool
@@ -484,7 +561,7 @@ func (t *Tokenizer) tokenizeMultilineComment() (string, error) {
 			t.Col = 0
 			t.Line++
 		} else if n == scanner.EOF {
-			return "", fmt.Errorf("unclosed multiline comment: %s at %+v", string(str), t.Pos())
+			return "", fmt.Errorf("%w: %s at %+v", ErrUnclosedMultilineComment, string(str), t.Pos())
 		} else {
 			t.Col++
 		}
@@ -209,6 +209,34 @@ select`,
 				},
 			},
 		},
+		{
+			name: "multi-line single quote string",
+			in:   "'a''\nb' c",
+			out: []*Token{
+				{
+					Kind:  SingleQuotedString,
+					Value: "'a'\nb'",
+					From:  Pos{Line: 0, Col: 0},
+					To:    Pos{Line: 1, Col: 2},
+				},
+				{
+					Kind:  Whitespace,
+					Value: " ",
+					From:  Pos{Line: 1, Col: 2},
+					To:    Pos{Line: 1, Col: 3},
+				},
+				{
+					Kind: SQLKeyword,
+					Value: &SQLWord{
+						Value:   "c",
+						Keyword: "C",
+						Kind:    dialect.Unmatched,
+					},
+					From: Pos{Line: 1, Col: 3},
+					To:   Pos{Line: 1, Col: 4},
+				},
+			},
+		},
 		{
 			name: "quoted string",
 			in:   `"SELECT"`,
@@ -876,3 +904,47 @@ test
 		}
 	})
 }
+
+func TestUTF16Col(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		col  int
+		want int
+	}{
+		{name: "ascii", line: "SELECT 1", col: 7, want: 7},
+		{name: "after a tab", line: "\tselect 1;", col: 10, want: 7},
+		{name: "tabs between words", line: "SELECT *\tFROM\tcty", col: 20, want: 14},
+		{name: "surrogate pair", line: "'😀' x", col: 4, want: 5},
+		{name: "past the end", line: "\tx", col: 6, want: 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := UTF16Col(c.line, c.col); got != c.want {
+				t.Errorf("expected %d, got %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestColOfUTF16(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		u    int
+		want int
+	}{
+		{name: "ascii", line: "SELECT 1", u: 7, want: 7},
+		{name: "after a tab", line: "\tselect 1;", u: 7, want: 10},
+		{name: "tabs between words", line: "SELECT *\tFROM\tcty", u: 14, want: 20},
+		{name: "surrogate pair", line: "'😀' x", u: 5, want: 4},
+		{name: "past the end", line: "\tx", u: 3, want: 6},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ColOfUTF16(c.line, c.u); got != c.want {
+				t.Errorf("expected %d, got %d", c.want, got)
+			}
+		})
+	}
+}